@@ -0,0 +1,67 @@
+package path
+
+// BoundPath is a path with some of its params already bound to
+// values. It is created with Builder.Bind.
+type BoundPath struct {
+	b    *Builder
+	name string
+	p    *pattern
+	base map[string]interface{}
+}
+
+// Bind returns a BoundPath for the named path with the params
+// in base bound to it, so that only the remaining params need
+// to be provided when it is resolved, eg:
+//
+//	bp := pb.Bind("show_dog", map[string]interface{}{"tenant": "acme"})
+//	for _, id := range ids {
+//	  path, err := bp.Resolve(map[string]interface{}{"id": id})
+//	  ...
+//	}
+//
+// The format of the path is captured when Bind is called, so
+// setting the path again afterwards does not change the
+// BoundPath. The Builder's options and defaults are still
+// read each time it is resolved. base is copied, so changing
+// it after Bind has no effect.
+func (b *Builder) Bind(name string, base map[string]interface{}) BoundPath {
+	bp := BoundPath{
+		b:    b,
+		name: name,
+		base: make(map[string]interface{}, len(base)),
+	}
+	bp.p, _ = b.get(name, b.ParamStyle)
+	for k, v := range base {
+		bp.base[k] = v
+	}
+	return bp
+}
+
+// Resolve builds the path with extra merged over the bound
+// params, the same as StrictPath. Params in extra take
+// precedence over bound params with the same key. A
+// *NotFoundError is returned if no path existed with the name
+// when it was bound.
+func (bp BoundPath) Resolve(extra map[string]interface{}) (string, error) {
+	if bp.p == nil {
+		return "", &NotFoundError{Name: bp.name}
+	}
+	params := bp.base
+	if len(extra) > 0 {
+		params = make(map[string]interface{}, len(bp.base)+len(extra))
+		for k, v := range bp.base {
+			params[k] = v
+		}
+		for k, v := range extra {
+			params[k] = v
+		}
+	}
+	buf := bufPool.Get().(*[]byte)
+	defer bufPool.Put(buf)
+	ret, err := bp.b.appendPattern((*buf)[:0], bp.p, params, bp.b.config())
+	*buf = ret
+	if err != nil {
+		return "", err
+	}
+	return string(ret), nil
+}
@@ -0,0 +1,44 @@
+package path
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestBuilder_Bind(t *testing.T) {
+	var pb Builder
+	pb.Set("show_dog", "/:tenant/dogs/:id")
+	base := map[string]interface{}{"tenant": "acme", "locale": "en"}
+	bp := pb.Bind("show_dog", base)
+	base["tenant"] = "changed"
+	pb.Set("show_dog", "/changed/:id")
+
+	tests := []struct {
+		name  string
+		extra map[string]interface{}
+		want  string
+	}{
+		{"extra", map[string]interface{}{"id": 1}, "/acme/dogs/1?locale=en"},
+		{"override", map[string]interface{}{"id": 2, "locale": "fr"}, "/acme/dogs/2?locale=fr"},
+		{"no extra", nil, "/acme/dogs/:id?locale=en"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := bp.Resolve(tc.extra)
+			if err != nil {
+				t.Fatalf("BoundPath.Resolve() error = %v, want %v", err, nil)
+			}
+			if got != tc.want {
+				t.Errorf("BoundPath.Resolve() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+
+	pb.IgnoreExtraParams = true
+	if got, _ := bp.Resolve(map[string]interface{}{"id": 1}); got != "/acme/dogs/1" {
+		t.Errorf("BoundPath.Resolve() = %v, want %v", got, "/acme/dogs/1")
+	}
+	if _, err := pb.Bind("fake_path", nil).Resolve(nil); !errors.Is(err, ErrNotFound) {
+		t.Errorf("BoundPath.Resolve() error = %v, want %v", err, ErrNotFound)
+	}
+}
@@ -0,0 +1,37 @@
+package path
+
+// std is the Builder used by the package-level functions.
+var std Builder
+
+// Default returns the Builder used by the package-level
+// functions such as Set and Path. It can be used to change
+// the default Builder's options, eg:
+//
+//	path.Default().IgnoreExtraParams = true
+func Default() *Builder {
+	return &std
+}
+
+// Set is used to set a named path on the default Builder.
+func Set(name, format string, opts ...RouteOption) {
+	std.Set(name, format, opts...)
+}
+
+// Path is used to retrieve a named path from the default
+// Builder or return an empty string if no path exists with
+// that name.
+func Path(name string, params map[string]interface{}) string {
+	return std.Path(name, params)
+}
+
+// StrictPath is used to retrieve a named path from the
+// default Builder or return an error if no path exists with
+// that name.
+func StrictPath(name string, params map[string]interface{}) (string, error) {
+	return std.StrictPath(name, params)
+}
+
+// PathE is the same as StrictPath.
+func PathE(name string, params map[string]interface{}) (string, error) {
+	return std.PathE(name, params)
+}
@@ -0,0 +1,55 @@
+package path
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestDefault(t *testing.T) {
+	if Default() != &std {
+		t.Errorf("Default() = %p, want %p", Default(), &std)
+	}
+}
+
+func TestSet(t *testing.T) {
+	Set("test_set_dog", "/dogs/:id")
+	if !std.Has("test_set_dog") {
+		t.Errorf("Set(%v) didn't persist", "test_set_dog")
+	}
+}
+
+func TestPath(t *testing.T) {
+	Set("test_path_dog", "/dogs/:id")
+	got := Path("test_path_dog", map[string]interface{}{"id": 123})
+	if want := "/dogs/123"; got != want {
+		t.Errorf("Path() = %v, want %v", got, want)
+	}
+	if got := Path("fake_path", nil); got != "" {
+		t.Errorf("Path() = %v, want %v", got, "")
+	}
+}
+
+func TestStrictPath(t *testing.T) {
+	Set("test_strict_path_dog", "/dogs/:id")
+	got, err := StrictPath("test_strict_path_dog", map[string]interface{}{"id": 123})
+	if err != nil {
+		t.Fatalf("StrictPath() error = %v, want %v", err, nil)
+	}
+	if want := "/dogs/123"; got != want {
+		t.Errorf("StrictPath() = %v, want %v", got, want)
+	}
+	if _, err := StrictPath("fake_path", nil); !errors.Is(err, ErrNotFound) {
+		t.Errorf("StrictPath() error = %v, want %v", err, ErrNotFound)
+	}
+}
+
+func TestPathE(t *testing.T) {
+	Set("test_path_e_dog", "/dogs/:id")
+	got, err := PathE("test_path_e_dog", map[string]interface{}{"id": 123})
+	if err != nil {
+		t.Fatalf("PathE() error = %v, want %v", err, nil)
+	}
+	if want := "/dogs/123"; got != want {
+		t.Errorf("PathE() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,96 @@
+package path
+
+import "sort"
+
+// Explanation describes how the params provided to Explain
+// would be used to build a path. Each field is sorted.
+type Explanation struct {
+	// PathParams are the params that fill a param in the
+	// path.
+	PathParams []string
+	// QueryParams are the params that are added to the query.
+	QueryParams []string
+	// UnusedParams are the params that are used for neither,
+	// eg because IgnoreExtraParams is set.
+	UnusedParams []string
+	// MissingParams are the params in the path that weren't
+	// provided a value.
+	MissingParams []string
+}
+
+// Explain reports how the params would be used if they were
+// used to build the named path, without building it. Defaults
+// set with SetDefault and params found by the ParamResolver
+// are included, as are the options of the path. This is
+// intended to help debug why a param ended up in the query or
+// disappeared entirely. A *NotFoundError is
+// returned if no path exists with the name provided, the
+// error for a path that can never be built, such as one with
+// a cycle, and an *ExtraParamsError if DisallowExtraParams is
+// set and building the path would return one.
+func (b *Builder) Explain(name string, params map[string]interface{}) (Explanation, error) {
+	var e Explanation
+	p, ok := b.get(name, b.ParamStyle)
+	if !ok {
+		return e, &NotFoundError{Name: name}
+	}
+	if p.err != nil {
+		return e, p.err
+	}
+	cfg := b.config()
+	p.opts.apply(&cfg)
+	cfg.opts.apply(&cfg)
+	if cfg.disallow {
+		if err := p.checkExtra(params, cfg); err != nil {
+			return e, err
+		}
+	}
+	params = withResolved(p.keys, params, cfg.resolver)
+	params = b.withDefaults(params)
+	keys := p.keys
+	if cfg.matrix && p.matrix != nil {
+		keys = p.matrix
+	}
+	for k, v := range params {
+		v, inQuery := unwrapInQuery(v)
+		inPath := keys[k]
+		if inPath && v == nil {
+			// Reported as missing below
+			continue
+		}
+		if inPath {
+			e.PathParams = append(e.PathParams, k)
+		}
+		switch {
+		case inPath && !inQuery:
+		case !cfg.query, cfg.omitEmpty && (v == nil || cfg.str(v) == ""), cfg.bareBools && v == false:
+			if !inPath {
+				e.UnusedParams = append(e.UnusedParams, k)
+			}
+		default:
+			e.QueryParams = append(e.QueryParams, k)
+		}
+	}
+	optional := make(map[string]bool)
+	for _, seg := range p.segments {
+		if seg.optional {
+			optional[seg.key] = true
+		}
+	}
+	for _, k := range p.names() {
+		if optional[k] {
+			// Optional params are left out when missing
+			continue
+		}
+		if _, ok := lookup(params, k); !ok {
+			if _, ok := p.defaults[k]; !ok {
+				e.MissingParams = append(e.MissingParams, k)
+			}
+		}
+	}
+	sort.Strings(e.PathParams)
+	sort.Strings(e.QueryParams)
+	sort.Strings(e.UnusedParams)
+	sort.Strings(e.MissingParams)
+	return e, nil
+}
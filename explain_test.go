@@ -0,0 +1,140 @@
+package path
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestBuilder_Explain(t *testing.T) {
+	tests := []struct {
+		name    string
+		builder *Builder
+		params  map[string]interface{}
+		want    Explanation
+	}{
+		{
+			name:    "path and query",
+			builder: &Builder{},
+			params:  map[string]interface{}{"id": 1, "page": 2},
+			want: Explanation{
+				PathParams:    []string{"id"},
+				QueryParams:   []string{"page"},
+				MissingParams: []string{"toy_id"},
+			},
+		},
+		{
+			name:    "ignored",
+			builder: &Builder{IgnoreExtraParams: true},
+			params:  map[string]interface{}{"id": 1, "toy_id": 2, "page": 2},
+			want: Explanation{
+				PathParams:   []string{"id", "toy_id"},
+				UnusedParams: []string{"page"},
+			},
+		},
+		{
+			name:    "in query",
+			builder: &Builder{},
+			params:  map[string]interface{}{"id": InQuery(1), "toy_id": 2},
+			want: Explanation{
+				PathParams:  []string{"id", "toy_id"},
+				QueryParams: []string{"id"},
+			},
+		},
+		{
+			name:    "bare bool flags",
+			builder: &Builder{BareBoolFlags: true},
+			params:  map[string]interface{}{"id": 1, "toy_id": 2, "new": true, "old": false},
+			want: Explanation{
+				PathParams:   []string{"id", "toy_id"},
+				QueryParams:  []string{"new"},
+				UnusedParams: []string{"old"},
+			},
+		},
+		{
+			name:    "omit empty",
+			builder: &Builder{OmitEmptyQuery: true},
+			params:  map[string]interface{}{"id": 1, "toy_id": 2, "q": ""},
+			want: Explanation{
+				PathParams:   []string{"id", "toy_id"},
+				UnusedParams: []string{"q"},
+			},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.builder.Set("show_toy", "/dogs/:id/toys/:toy_id")
+			got, err := tc.builder.Explain("show_toy", tc.params)
+			if err != nil {
+				t.Fatalf("Builder.Explain() error = %v, want %v", err, nil)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("Builder.Explain() = %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+
+	var pb Builder
+	pb.Set("search", "/search/:page=1", IgnoreExtra())
+	pb.SetDefault("locale", "en")
+	got, err := pb.Explain("search", map[string]interface{}{"q": "dogs"})
+	if err != nil {
+		t.Fatalf("Builder.Explain() error = %v, want %v", err, nil)
+	}
+	want := Explanation{UnusedParams: []string{"locale", "q"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Builder.Explain() = %+v, want %+v", got, want)
+	}
+
+	resolved := Builder{ParamResolver: func(key string) (interface{}, bool) {
+		return 5, key == "id"
+	}}
+	resolved.Set("show_widget", "/widgets/:id")
+	got, err = resolved.Explain("show_widget", nil)
+	if err != nil {
+		t.Fatalf("Builder.Explain() error = %v, want %v", err, nil)
+	}
+	want = Explanation{PathParams: []string{"id"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Builder.Explain() = %+v, want %+v", got, want)
+	}
+
+	strict := Builder{DisallowExtraParams: true}
+	strict.Set("show_dog", "/dogs/:id")
+	_, err = strict.Explain("show_dog", map[string]interface{}{"id": 1, "page": 2})
+	if !errors.Is(err, ErrExtraParams) {
+		t.Errorf("Builder.Explain() error = %v, want %v", err, ErrExtraParams)
+	}
+	_, wantErr := strict.StrictPath("show_dog", map[string]interface{}{"id": 1, "page": 2})
+	if !reflect.DeepEqual(err, wantErr) {
+		t.Errorf("Builder.Explain() error = %v, want %v", err, wantErr)
+	}
+
+	optional := Builder{RequireAllParams: true}
+	optional.Set("show_post", "/posts/:id/:slug?")
+	got, err = optional.Explain("show_post", map[string]interface{}{"id": 1})
+	if err != nil {
+		t.Fatalf("Builder.Explain() error = %v, want %v", err, nil)
+	}
+	want = Explanation{PathParams: []string{"id"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Builder.Explain() = %+v, want %+v", got, want)
+	}
+	if _, err := optional.StrictPath("show_post", map[string]interface{}{"id": 1}); err != nil {
+		t.Errorf("Builder.StrictPath() error = %v, want %v", err, nil)
+	}
+
+	var broken Builder
+	broken.Set("catch_all", "/files/*filepath/edit")
+	broken.Set("cyc", "{cyc}/a")
+	if _, err := broken.Explain("catch_all", nil); !errors.Is(err, ErrCatchAllPos) {
+		t.Errorf("Builder.Explain() error = %v, want %v", err, ErrCatchAllPos)
+	}
+	if _, err := broken.Explain("cyc", nil); !errors.Is(err, ErrCycle) {
+		t.Errorf("Builder.Explain() error = %v, want %v", err, ErrCycle)
+	}
+
+	if _, err := pb.Explain("fake_path", nil); err == nil {
+		t.Errorf("Builder.Explain() error = %v, want an error", err)
+	}
+}
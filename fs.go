@@ -0,0 +1,70 @@
+//go:build go1.16
+// +build go1.16
+
+package path
+
+import (
+	"bufio"
+	"fmt"
+	"io/fs"
+	"strings"
+)
+
+// LoadFS sets the paths in every file in fsys whose name
+// matches glob, as used by fs.Glob. Each line of a file is the
+// name of a path and its format separated by a tab, eg:
+//
+//	show_dog	/dogs/:id
+//	edit_dog	/dogs/:id/edit
+//
+// Blank lines are ignored. This works well with embed, eg:
+//
+//	//go:embed routes/*.route
+//	var routes embed.FS
+//
+//	err := pb.LoadFS(routes, "routes/*.route")
+//
+// If a file can't be read or a line is invalid, or a name is
+// in more than one line, an error with the name of the file
+// and the line number is returned and no paths are set.
+func (b *Builder) LoadFS(fsys fs.FS, glob string) error {
+	files, err := fs.Glob(fsys, glob)
+	if err != nil {
+		return err
+	}
+	paths := make(map[string]string)
+	seen := make(map[string]string)
+	for _, file := range files {
+		f, err := fsys.Open(file)
+		if err != nil {
+			return err
+		}
+		s := bufio.NewScanner(f)
+		for n := 1; s.Scan(); n++ {
+			line := strings.TrimSuffix(s.Text(), "\r")
+			if strings.TrimSpace(line) == "" {
+				continue
+			}
+			pos := fmt.Sprintf("%s:%d", file, n)
+			i := strings.IndexByte(line, '\t')
+			if i <= 0 {
+				f.Close()
+				return fmt.Errorf("path: %s: expected a name and format separated by a tab", pos)
+			}
+			name, format := line[:i], line[i+1:]
+			if prev, ok := seen[name]; ok {
+				f.Close()
+				return fmt.Errorf("path: %s: %q was already set at %s", pos, name, prev)
+			}
+			seen[name] = pos
+			paths[name] = format
+		}
+		err = s.Err()
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("path: %s: %w", file, err)
+		}
+	}
+	b.SetAll(paths)
+	return nil
+}
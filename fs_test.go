@@ -0,0 +1,59 @@
+//go:build go1.16
+// +build go1.16
+
+package path
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestBuilder_LoadFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"routes/dogs.route":  {Data: []byte("show_dog\t/dogs/:id\n\nedit_dog\t/dogs/:id/edit\r\n")},
+		"routes/cats.route":  {Data: []byte("show_cat\t/cats/:id")},
+		"routes/README.md":   {Data: []byte("not a route")},
+		"bad/space.route":    {Data: []byte("show_dog\t/dogs/:id\nshow_cat /cats/:id\n")},
+		"dupe/a.route":       {Data: []byte("show_dog\t/dogs/:id\n")},
+		"dupe/b.route":       {Data: []byte("\nshow_dog\t/v2/dogs/:id\n")},
+		"bad/noname/a.route": {Data: []byte("\t/dogs/:id\n")},
+	}
+	tests := []struct {
+		name    string
+		glob    string
+		want    map[string]string
+		wantErr string
+	}{
+		{"routes", "routes/*.route", map[string]string{
+			"show_dog": "/dogs/:id",
+			"edit_dog": "/dogs/:id/edit",
+			"show_cat": "/cats/:id",
+		}, ""},
+		{"no matches", "none/*.route", map[string]string{}, ""},
+		{"missing tab", "bad/*.route", map[string]string{}, "bad/space.route:2"},
+		{"missing name", "bad/noname/*.route", map[string]string{}, "bad/noname/a.route:1"},
+		{"duplicate", "dupe/*.route", map[string]string{}, "dupe/b.route:2"},
+		{"bad glob", "[", map[string]string{}, "syntax error"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var pb Builder
+			err := pb.LoadFS(fsys, tc.glob)
+			if tc.wantErr == "" && err != nil {
+				t.Fatalf("Builder.LoadFS() error = %v, want %v", err, nil)
+			}
+			if tc.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tc.wantErr)) {
+				t.Fatalf("Builder.LoadFS() error = %v, want an error containing %v", err, tc.wantErr)
+			}
+			if got := len(pb.Names()); got != len(tc.want) {
+				t.Errorf("len(Builder.Names()) = %v, want %v", got, len(tc.want))
+			}
+			for name, want := range tc.want {
+				if got, _ := pb.Format(name); got != want {
+					t.Errorf("Builder.Format(%v) = %v, want %v", name, got, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package path
+
+import (
+	"fmt"
+	"html/template"
+)
+
+// FuncMap returns a template.FuncMap that can be used to
+// build paths within templates. It includes the following
+// functions:
+//
+//	path - takes the name of a path followed by pairs of
+//	       param keys and values, eg:
+//
+//	         {{path "show_dog" "id" .Dog.ID}}
+//
+// Just like Path, an empty string is returned when no path
+// exists with the provided name. An error is returned when
+// the params aren't provided as key value pairs.
+func (b *Builder) FuncMap() template.FuncMap {
+	return template.FuncMap{
+		"path": func(name string, pairs ...interface{}) (string, error) {
+			params, err := pairsToParams(pairs)
+			if err != nil {
+				return "", err
+			}
+			return b.Path(name, params), nil
+		},
+	}
+}
+
+// pairsToParams converts alternating keys and values into a
+// map of params.
+func pairsToParams(pairs []interface{}) (map[string]interface{}, error) {
+	if len(pairs)%2 != 0 {
+		return nil, fmt.Errorf("path: params must be key value pairs, got %d args", len(pairs))
+	}
+	params := make(map[string]interface{}, len(pairs)/2)
+	for i := 0; i < len(pairs); i += 2 {
+		k, ok := pairs[i].(string)
+		if !ok {
+			return nil, fmt.Errorf("path: param keys must be strings, got %T", pairs[i])
+		}
+		params[k] = pairs[i+1]
+	}
+	return params, nil
+}
+
+// HTMLPath is like StrictPath, but the path is returned as a
+// template.URL, which html/template trusts as a URL. This
+// keeps html/template from filtering or escaping the path
+// again when it is used in an attribute such as href, eg:
+//
+//	<a href="{{.DogURL}}">
+//
+// Params are escaped when the path is built, but catch-all
+// params and params built with RawPathParams set are not, so
+// HTMLPath should not be used when a path can start with an
+// untrusted value such as a `javascript:` URL.
+func (b *Builder) HTMLPath(name string, params map[string]interface{}) (template.URL, error) {
+	ret, err := b.StrictPath(name, params)
+	if err != nil {
+		return "", err
+	}
+	return template.URL(ret), nil
+}
@@ -0,0 +1,93 @@
+package path
+
+import (
+	"errors"
+	"html/template"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestBuilder_FuncMap(t *testing.T) {
+	var pb Builder
+	pb.Set("show_dog", "/dogs/:id")
+	tests := []struct {
+		name    string
+		tpl     string
+		want    string
+		wantErr bool
+	}{
+		{"no params", `{{path "show_dog"}}`, "/dogs/:id", false},
+		{"params", `{{path "show_dog" "id" .ID}}`, "/dogs/123", false},
+		{"query params", `{{path "show_dog" "id" .ID "age" 12}}`, "/dogs/123?age=12", false},
+		{"missing path", `{{path "fake_path" "id" .ID}}`, "", false},
+		{"odd params", `{{path "show_dog" "id"}}`, "", true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tpl := template.Must(template.New("").Funcs(pb.FuncMap()).Parse(tc.tpl))
+			var sb strings.Builder
+			err := tpl.Execute(&sb, struct{ ID int }{123})
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("Execute() err = %v, wantErr %v", err, tc.wantErr)
+			}
+			if tc.wantErr {
+				return
+			}
+			if got := sb.String(); got != tc.want {
+				t.Errorf("Execute() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func Test_pairsToParams(t *testing.T) {
+	tests := []struct {
+		name    string
+		pairs   []interface{}
+		want    map[string]interface{}
+		wantErr bool
+	}{
+		{"empty", nil, map[string]interface{}{}, false},
+		{"pairs", []interface{}{"id", 123, "name", "felix"}, map[string]interface{}{"id": 123, "name": "felix"}, false},
+		{"odd", []interface{}{"id"}, nil, true},
+		{"non-string key", []interface{}{1, 2}, nil, true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := pairsToParams(tc.pairs)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("pairsToParams() err = %v, wantErr %v", err, tc.wantErr)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("pairsToParams() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestBuilder_HTMLPath(t *testing.T) {
+	var pb Builder
+	pb.Set("show_dog", "/dogs/:name")
+	got, err := pb.HTMLPath("show_dog", map[string]interface{}{"name": "rex <3", "a": "1", "b": "2"})
+	if err != nil {
+		t.Fatalf("Builder.HTMLPath() error = %v, want %v", err, nil)
+	}
+	var _ template.URL = got
+	if want := template.URL("/dogs/rex%20%3C3?a=1&b=2"); got != want {
+		t.Errorf("Builder.HTMLPath() = %v, want %v", got, want)
+	}
+
+	tpl := template.Must(template.New("").Parse(`<a href="{{.}}">`))
+	var sb strings.Builder
+	if err := tpl.Execute(&sb, got); err != nil {
+		t.Fatalf("Execute() error = %v, want %v", err, nil)
+	}
+	if want := `<a href="/dogs/rex%20%3C3?a=1&amp;b=2">`; sb.String() != want {
+		t.Errorf("Execute() = %v, want %v", sb.String(), want)
+	}
+
+	if _, err := pb.HTMLPath("fake_path", nil); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Builder.HTMLPath() error = %v, want %v", err, ErrNotFound)
+	}
+}
@@ -0,0 +1,116 @@
+package path
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/format"
+	"io"
+	"sort"
+	"strconv"
+)
+
+// GenerateGo writes the source of a Go file in the package pkg
+// that declares a variable named Paths, which is a *Builder
+// with every path that is set on b, eg:
+//
+//	var Paths = path.MustNew(map[string]string{
+//		"show_dog": "/dogs/:id",
+//	})
+//
+// The ParamStyle, BaseURL, Prefix, IgnoreExtraParams, and
+// RequireAllParams options are included when they are set.
+// Other options, such as Formatter, can't be written as code
+// and must be set on Paths separately. The output is formatted
+// with gofmt, and an error is returned if pkg isn't a valid
+// package name. Each format is checked like SetValid first,
+// since MustNew would panic on an invalid one, and the error
+// for the first invalid format is returned.
+//
+// An error is also returned if b has anything the generated
+// Builder would silently lose: a Separator other than `/`,
+// CaseInsensitiveNames, paths set with SetID, SetFunc, or
+// SetTyped, paths set with RouteOptions, or defaults set with
+// SetDefault.
+func (b *Builder) GenerateGo(pkg string, w io.Writer) error {
+	if err := b.generatable(); err != nil {
+		return err
+	}
+	routes := make(map[string]string)
+	b.Walk(func(name, format string) error {
+		routes[name] = format
+		return nil
+	})
+	// Validate the paths the same way MustNew will
+	if err := (&Builder{ParamStyle: b.ParamStyle}).setValid(routes); err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	buf.WriteString("// Code generated by path.GenerateGo. DO NOT EDIT.\n\n")
+	fmt.Fprintf(&buf, "package %s\n\n", pkg)
+	buf.WriteString("import \"github.com/joncalhoun/path\"\n\n")
+	buf.WriteString("// Paths is a Builder with every generated path set.\n")
+	buf.WriteString("var Paths = path.MustNew(map[string]string{\n")
+	names := make([]string, 0, len(routes))
+	for name := range routes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(&buf, "%s: %s,\n", strconv.Quote(name), strconv.Quote(routes[name]))
+	}
+	buf.WriteString("}")
+	if b.ParamStyle == BraceStyle {
+		buf.WriteString(", path.WithParamStyle(path.BraceStyle)")
+	}
+	if b.BaseURL != "" {
+		fmt.Fprintf(&buf, ", path.WithBaseURL(%s)", strconv.Quote(b.BaseURL))
+	}
+	if b.Prefix != "" {
+		fmt.Fprintf(&buf, ", path.WithPrefix(%s)", strconv.Quote(b.Prefix))
+	}
+	if b.IgnoreExtraParams {
+		buf.WriteString(", path.WithIgnoreExtraParams(true)")
+	}
+	if b.RequireAllParams {
+		buf.WriteString(", path.WithRequireAllParams(true)")
+	}
+	buf.WriteString(")\n")
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return fmt.Errorf("path: generating Go for package %q: %w", pkg, err)
+	}
+	_, err = w.Write(src)
+	return err
+}
+
+// generatable returns an error describing the first setting of
+// b that GenerateGo can't write as code.
+func (b *Builder) generatable() error {
+	if b.separator() != "/" {
+		return fmt.Errorf("path: GenerateGo can't generate the Separator %q", b.Separator)
+	}
+	if b.CaseInsensitiveNames {
+		return errors.New("path: GenerateGo can't generate CaseInsensitiveNames")
+	}
+	t := b.table()
+	t.m.RLock()
+	defer t.m.RUnlock()
+	if len(t.ids) > 0 {
+		return errors.New("path: GenerateGo can't generate paths set with SetID")
+	}
+	if len(t.defaults) > 0 {
+		return errors.New("path: GenerateGo can't generate defaults set with SetDefault")
+	}
+	for name, p := range t.paths {
+		switch {
+		case p.fn != nil:
+			return fmt.Errorf("path: %q: GenerateGo can't generate paths set with SetFunc", name)
+		case p.types != nil:
+			return fmt.Errorf("path: %q: GenerateGo can't generate paths set with SetTyped", name)
+		case p.opts != Options{}:
+			return fmt.Errorf("path: %q: GenerateGo can't generate RouteOptions", name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package path
+
+import (
+	"bytes"
+	"go/format"
+	"reflect"
+	"testing"
+)
+
+func TestBuilder_GenerateGo(t *testing.T) {
+	pb := Builder{ParamStyle: BraceStyle, BaseURL: "https://example.com"}
+	pb.Set("show_dog", "/dogs/{id}")
+	pb.Set("quoted", "/say/\"hi\"")
+	var buf bytes.Buffer
+	if err := pb.GenerateGo("routes", &buf); err != nil {
+		t.Fatalf("Builder.GenerateGo() error = %v, want %v", err, nil)
+	}
+	want := `// Code generated by path.GenerateGo. DO NOT EDIT.
+
+package routes
+
+import "github.com/joncalhoun/path"
+
+// Paths is a Builder with every generated path set.
+var Paths = path.MustNew(map[string]string{
+	"quoted":   "/say/\"hi\"",
+	"show_dog": "/dogs/{id}",
+}, path.WithParamStyle(path.BraceStyle), path.WithBaseURL("https://example.com"))
+`
+	if got := buf.String(); got != want {
+		t.Errorf("Builder.GenerateGo() = %v, want %v", got, want)
+	}
+	if src, err := format.Source(buf.Bytes()); err != nil || !bytes.Equal(src, buf.Bytes()) {
+		t.Errorf("Builder.GenerateGo() output isn't gofmt-clean, err = %v", err)
+	}
+
+	var empty Builder
+	buf.Reset()
+	if err := empty.GenerateGo("routes", &buf); err != nil {
+		t.Fatalf("Builder.GenerateGo() error = %v, want %v", err, nil)
+	}
+	if err := empty.GenerateGo("not a package", &buf); err == nil {
+		t.Errorf("Builder.GenerateGo() error = %v, want an error", err)
+	}
+
+	// The generated Builder builds the same paths as pb
+	generated := MustNew(pb.Snapshot(), WithParamStyle(BraceStyle), WithBaseURL("https://example.com"))
+	for _, name := range pb.Names() {
+		params := map[string]interface{}{"id": 1, "page": 2}
+		want, wantErr := pb.StrictPath(name, params)
+		got, err := generated.StrictPath(name, params)
+		if got != want || !reflect.DeepEqual(err, wantErr) {
+			t.Errorf("Builder.StrictPath(%v) = %v, %v, want %v, %v", name, got, err, want, wantErr)
+		}
+	}
+
+	unsupported := map[string]func(*Builder){
+		"separator":        func(b *Builder) { b.Separator = "."; b.Set("a", "a.:id") },
+		"case insensitive": func(b *Builder) { b.CaseInsensitiveNames = true },
+		"id":               func(b *Builder) { b.SetID(1, "/dogs/:id") },
+		"func":             func(b *Builder) { b.SetFunc("a", func() string { return "/a" }) },
+		"typed":            func(b *Builder) { b.SetTyped("a", "/a/:id", map[string]string{"id": "int"}) },
+		"route options":    func(b *Builder) { b.Set("a", "/a", IgnoreExtra()) },
+		"defaults":         func(b *Builder) { b.SetDefault("locale", "en") },
+	}
+	for name, set := range unsupported {
+		var b Builder
+		set(&b)
+		buf.Reset()
+		if err := b.GenerateGo("routes", &buf); err == nil {
+			t.Errorf("Builder.GenerateGo() error = %v, want an error for %v", err, name)
+		}
+	}
+
+	for _, format := range []string{"/a/*b/c", "/dogs/:"} {
+		var invalid Builder
+		invalid.Set("invalid", format)
+		buf.Reset()
+		if err := invalid.GenerateGo("routes", &buf); err == nil {
+			t.Errorf("Builder.GenerateGo() error = %v, want an error for %v", err, format)
+		}
+		if buf.Len() != 0 {
+			t.Errorf("Builder.GenerateGo() wrote %v, want nothing", buf.String())
+		}
+	}
+}
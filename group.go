@@ -0,0 +1,88 @@
+package path
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Group is used to set many paths that share a common prefix.
+// It is created with Builder.Group.
+type Group struct {
+	b      *Builder
+	prefix string
+}
+
+// Group returns a Group that sets paths on b with prefix
+// added to the start of every format. Eg:
+//
+//	g := pb.Group("/admin")
+//	g.Set("admin_users", "/users")
+//
+// sets the path named "admin_users" with the format
+// `/admin/users`. Only the format is prefixed; names are used
+// as-is. Exactly one slash is used between the prefix and the
+// format, regardless of whether either has a slash at the
+// join.
+func (b *Builder) Group(prefix string) *Group {
+	return &Group{
+		b:      b,
+		prefix: prefix,
+	}
+}
+
+// Group returns a nested Group whose prefix is the prefix of g
+// followed by prefix.
+func (g *Group) Group(prefix string) *Group {
+	return g.b.Group(join(g.prefix, prefix))
+}
+
+// Set is used to set a named path whose format is the Group's
+// prefix followed by format.
+func (g *Group) Set(name, format string, opts ...RouteOption) {
+	g.b.Set(name, join(g.prefix, format), opts...)
+}
+
+// RouteSet is a set of names and formats that can be defined
+// without a Builder, eg by a package that doesn't know where
+// its paths will be mounted. A RouteSet is added to a Builder
+// with Register:
+//
+//	admin := path.RouteSet{"admin_users": "/users"}
+//	err := pb.Register(admin.Mount("/admin"))
+type RouteSet map[string]string
+
+// Mount returns a copy of rs with prefix added to the start of
+// every format, the same as a Group. rs is not changed.
+func (rs RouteSet) Mount(prefix string) RouteSet {
+	mounted := make(RouteSet, len(rs))
+	for name, format := range rs {
+		mounted[name] = join(prefix, format)
+	}
+	return mounted
+}
+
+// Register sets every path in rs on b. If any name in rs is
+// already set on b, an error wrapping ErrDuplicate is returned
+// and none of the paths are set. The paths are set while
+// holding the lock, so concurrent readers will see either all
+// of them or none.
+func (b *Builder) Register(rs RouteSet) error {
+	b = b.table()
+	names := make([]string, 0, len(rs))
+	for name := range rs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	b.m.Lock()
+	defer b.m.Unlock()
+	b.init()
+	for _, name := range names {
+		if _, ok := b.paths[b.normalize(name)]; ok {
+			return fmt.Errorf("path: %q: %w", name, ErrDuplicate)
+		}
+	}
+	for _, name := range names {
+		b.paths[b.normalize(name)] = compile(rs[name], b.ParamStyle, b.Separator)
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package path
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestBuilder_Group(t *testing.T) {
+	var pb Builder
+	admin := pb.Group("/admin")
+	admin.Set("admin_users", "/users")
+	admin.Set("admin_dogs", "dogs/:id")
+	admin.Set("admin_home", "")
+	pb.Group("/admin/").Set("admin_cats", "/cats")
+	admin.Group("/reports/").Set("admin_report", "/:id")
+	tests := map[string]string{
+		"admin_users":  "/admin/users",
+		"admin_dogs":   "/admin/dogs/:id",
+		"admin_home":   "/admin",
+		"admin_cats":   "/admin/cats",
+		"admin_report": "/admin/reports/:id",
+	}
+	for name, want := range tests {
+		got, ok := pb.Format(name)
+		if !ok {
+			t.Errorf("Builder.Format(%v) not found", name)
+			continue
+		}
+		if got != want {
+			t.Errorf("Builder.Format(%v) = %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestRouteSet_Mount(t *testing.T) {
+	rs := RouteSet{"users": "/users", "show_user": "users/:id", "home": ""}
+	got := rs.Mount("/admin")
+	want := RouteSet{"users": "/admin/users", "show_user": "/admin/users/:id", "home": "/admin"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("RouteSet.Mount() = %v, want %v", got, want)
+	}
+	if rs["users"] != "/users" {
+		t.Errorf("RouteSet.Mount() changed the original to %v", rs)
+	}
+}
+
+func TestBuilder_Register(t *testing.T) {
+	var pb Builder
+	pb.Set("home", "/")
+	if err := pb.Register(RouteSet{"show_dog": "/dogs/:id"}.Mount("/v1")); err != nil {
+		t.Fatalf("Builder.Register() error = %v, want %v", err, nil)
+	}
+	if got, want := pb.Path("show_dog", map[string]interface{}{"id": 1}), "/v1/dogs/1"; got != want {
+		t.Errorf("Builder.Path() = %v, want %v", got, want)
+	}
+
+	err := pb.Register(RouteSet{"show_cat": "/cats/:id", "show_dog": "/dogs/:id"})
+	if !errors.Is(err, ErrDuplicate) {
+		t.Errorf("Builder.Register() error = %v, want %v", err, ErrDuplicate)
+	}
+	if pb.Has("show_cat") {
+		t.Errorf("Builder.Register() set paths despite a duplicate")
+	}
+	if got, _ := pb.Format("show_dog"); got != "/v1/dogs/:id" {
+		t.Errorf("Builder.Format() = %v, want %v", got, "/v1/dogs/:id")
+	}
+}
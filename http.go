@@ -0,0 +1,91 @@
+package path
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+// contextKey is the type of the key used to store a Builder in
+// a context. It is unexported so that no other package can use
+// the same key, even by accident.
+type contextKey struct{}
+
+// Redirect replies to the request with a redirect to the named
+// path, using StrictPath to build it. The path is used as the
+// value of the Location header, so if BaseURL is set the
+// redirect will be to an absolute URL.
+//
+// code should be one of the 3xx redirect status codes, such as
+// http.StatusFound (302) or http.StatusSeeOther (303) after a
+// form submission, or http.StatusMovedPermanently (301) and
+// http.StatusPermanentRedirect (308) for permanent redirects.
+//
+// If the path cannot be built, eg because no path exists with
+// the name provided, a 500 Internal Server Error is written
+// instead.
+func (b *Builder) Redirect(w http.ResponseWriter, r *http.Request, name string, params map[string]interface{}, code int) {
+	path, err := b.StrictPath(name, params)
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	http.Redirect(w, r, path, code)
+}
+
+// Middleware returns a handler that adds a request-scoped
+// Builder to the context of each request before calling next.
+// The BaseURL of the request's Builder is set to the scheme
+// and host of the request, so handlers can build absolute URLs
+// for whichever host was requested, eg:
+//
+//	path.FromContext(r.Context()).Path("show_dog", params)
+//
+// The scheme is https if the request was received over TLS,
+// and http otherwise. The request's Builder copies the options
+// of b, but shares its paths and defaults rather than cloning
+// them, so it is cheap to create and setting a path on either
+// sets it on both.
+//
+// The host is taken from the Host header, which is set by the
+// client. Without hosts any value is used, so a client can
+// make the URLs point at a host of its choosing, eg to poison
+// a cache or a password reset link. When hosts are provided,
+// only a request for one of them, compared case-insensitively
+// and including any port, uses its own host; every other
+// request keeps the BaseURL of b.
+func (b *Builder) Middleware(next http.Handler, hosts ...string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c := b.options()
+		c.parent = b.table()
+		if allowed(r.Host, hosts) {
+			scheme := "http"
+			if r.TLS != nil {
+				scheme = "https"
+			}
+			c.BaseURL = scheme + "://" + r.Host
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), contextKey{}, c)))
+	})
+}
+
+// allowed returns whether or not host is one of hosts, or
+// true if hosts is empty.
+func allowed(host string, hosts []string) bool {
+	if len(hosts) == 0 {
+		return true
+	}
+	for _, h := range hosts {
+		if strings.EqualFold(h, host) {
+			return true
+		}
+	}
+	return false
+}
+
+// FromContext returns the Builder added to ctx by Middleware,
+// or nil if there isn't one.
+func FromContext(ctx context.Context) *Builder {
+	b, _ := ctx.Value(contextKey{}).(*Builder)
+	return b
+}
@@ -0,0 +1,104 @@
+package path
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestBuilder_Redirect(t *testing.T) {
+	var pb Builder
+	pb.Set("show_dog", "/dogs/:id")
+	tests := []struct {
+		name         string
+		path         string
+		params       map[string]interface{}
+		code         int
+		wantCode     int
+		wantLocation string
+	}{
+		{"found", "show_dog", map[string]interface{}{"id": 123}, http.StatusFound, http.StatusFound, "/dogs/123"},
+		{"see other", "show_dog", map[string]interface{}{"id": 123, "age": 12}, http.StatusSeeOther, http.StatusSeeOther, "/dogs/123?age=12"},
+		{"missing path", "fake_path", nil, http.StatusFound, http.StatusInternalServerError, ""},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodPost, "/dogs", nil)
+			pb.Redirect(w, r, tc.path, tc.params, tc.code)
+			if w.Code != tc.wantCode {
+				t.Errorf("Builder.Redirect() code = %v, want %v", w.Code, tc.wantCode)
+			}
+			if got := w.Header().Get("Location"); got != tc.wantLocation {
+				t.Errorf("Builder.Redirect() Location = %v, want %v", got, tc.wantLocation)
+			}
+		})
+	}
+}
+
+func TestBuilder_Middleware(t *testing.T) {
+	pb := Builder{Prefix: "/v1"}
+	pb.Set("show_dog", "/dogs/:id")
+	tests := []struct {
+		name  string
+		host  string
+		tls   bool
+		hosts []string
+		want  string
+	}{
+		{"http", "example.com", false, nil, "http://example.com/v1/dogs/123"},
+		{"https", "example.com:8443", true, nil, "https://example.com:8443/v1/dogs/123"},
+		{"allowed", "Example.com", true, []string{"example.com"}, "https://Example.com/v1/dogs/123"},
+		{"not allowed", "evil.com", false, []string{"example.com"}, "/v1/dogs/123"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var got string
+			h := pb.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = FromContext(r.Context()).Path("show_dog", map[string]interface{}{"id": 123})
+			}), tc.hosts...)
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.Host = tc.host
+			r.TLS = nil
+			if tc.tls {
+				r.TLS = &tls.ConnectionState{}
+			}
+			h.ServeHTTP(httptest.NewRecorder(), r)
+			if got != tc.want {
+				t.Errorf("Builder.Path() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+	if pb.BaseURL != "" {
+		t.Errorf("Builder.Middleware() changed the BaseURL to %v", pb.BaseURL)
+	}
+
+	// The request's Builder shares the paths and defaults of
+	// pb, and has every method of a Builder.
+	var got *url.URL
+	var clone *Builder
+	h := pb.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rb := FromContext(r.Context())
+		rb.Set("show_toy", "/toys/:id")
+		got, _ = rb.URL("show_cat", map[string]interface{}{"id": 1})
+		clone = rb.Clone()
+	}))
+	pb.Set("show_cat", "/cats/:id")
+	pb.SetDefault("locale", "en")
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "http://example.com/", nil))
+	if want := "http://example.com/v1/cats/1?locale=en"; got == nil || got.String() != want {
+		t.Errorf("Builder.URL() = %v, want %v", got, want)
+	}
+	if !pb.Has("show_toy") {
+		t.Errorf("Builder.Has() = %v, want %v", false, true)
+	}
+	if clone.BaseURL != "http://example.com" || !clone.Has("show_toy") {
+		t.Errorf("Builder.Clone() BaseURL = %v, Has() = %v, want %v, %v", clone.BaseURL, clone.Has("show_toy"), "http://example.com", true)
+	}
+	if got := FromContext(context.Background()); got != nil {
+		t.Errorf("FromContext() = %v, want %v", got, nil)
+	}
+}
@@ -0,0 +1,98 @@
+package path
+
+import "strconv"
+
+// SetID is like Set, but the path is identified by id rather
+// than by name. This allows paths to be identified by
+// constants, so that a typo is a compile error rather than a
+// missing path, eg:
+//
+//	const (
+//	  ShowDog = iota
+//	  EditDog
+//	)
+//
+//	pb.SetID(ShowDog, "/dogs/:id")
+//	pb.PathID(ShowDog, map[string]interface{}{"id": 123})
+//
+// Paths set with SetID are kept apart from named paths, so the
+// id 1 and the name "1" are different paths. They can
+// reference named paths like any other format, eg `{dogs}/:id`.
+func (b *Builder) SetID(id int, format string, opts ...RouteOption) {
+	b = b.table()
+	b.m.Lock()
+	defer b.m.Unlock()
+	p := compile(format, b.ParamStyle, b.Separator)
+	for _, opt := range opts {
+		opt(&p.opts)
+	}
+	if b.ids == nil {
+		b.ids = make(map[int]*pattern)
+	}
+	b.ids[id] = p
+}
+
+// PathID is like StrictPath, but builds the path set with
+// SetID for id. A *NotFoundError is returned if no path has
+// been set for id.
+func (b *Builder) PathID(id int, params map[string]interface{}) (string, error) {
+	cfg := b.config()
+	p, ok := b.getID(id, cfg.style)
+	if !ok {
+		return "", &NotFoundError{Name: "#" + strconv.Itoa(id)}
+	}
+	buf := bufPool.Get().(*[]byte)
+	defer bufPool.Put(buf)
+	ret, err := b.appendPattern((*buf)[:0], p, params, cfg)
+	*buf = ret
+	if err != nil {
+		return "", err
+	}
+	return string(ret), nil
+}
+
+// getID is like get, but for paths set with SetID. The Builder
+// must not be locked when getID is called.
+func (b *Builder) getID(id int, style ParamStyle) (*pattern, bool) {
+	b = b.table()
+	b.m.RLock()
+	p, ok := b.ids[id]
+	var raw, format string
+	var err error
+	if ok {
+		raw = p.raw()
+		format, err = b.resolve(raw, nil)
+	}
+	b.m.RUnlock()
+	if !ok {
+		return nil, false
+	}
+	if err != nil {
+		return &pattern{format: raw, err: err}, true
+	}
+	if !p.stale(style, b.separator(), raw, format) {
+		return p, true
+	}
+	b.m.Lock()
+	defer b.m.Unlock()
+	if b.ids[id] != p {
+		// The path was set again while unlocked
+		if p, ok = b.ids[id]; !ok {
+			return nil, false
+		}
+		raw = p.raw()
+		if format, err = b.resolve(raw, nil); err != nil {
+			return &pattern{format: raw, err: err}, true
+		}
+		if !p.stale(style, b.separator(), raw, format) {
+			return p, true
+		}
+	}
+	c := compile(format, style, b.Separator)
+	if raw != format {
+		c.src = raw
+	}
+	c.opts = p.opts
+	b.ids[id] = c
+	return c, true
+}
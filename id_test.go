@@ -0,0 +1,58 @@
+package path
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestBuilder_PathID(t *testing.T) {
+	const (
+		showDog = iota
+		editDog
+		dogs
+	)
+	var pb Builder
+	pb.Set("dogs", "/dogs")
+	pb.Set("0", "/named")
+	pb.SetID(showDog, "/dogs/:id")
+	pb.SetID(editDog, "{dogs}/:id/edit", IgnoreExtra())
+	tests := []struct {
+		name    string
+		id      int
+		params  map[string]interface{}
+		want    string
+		wantErr error
+	}{
+		{"path", showDog, map[string]interface{}{"id": 1, "page": 2}, "/dogs/1?page=2", nil},
+		{"route options and references", editDog, map[string]interface{}{"id": 1, "page": 2}, "/dogs/1/edit", nil},
+		{"not found", dogs, nil, "", ErrNotFound},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := pb.PathID(tc.id, tc.params)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("Builder.PathID() error = %v, want %v", err, tc.wantErr)
+			}
+			if got != tc.want {
+				t.Errorf("Builder.PathID() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+
+	pb.ParamStyle = BraceStyle
+	pb.SetID(showDog, "/dogs/{id}")
+	if got, _ := pb.PathID(showDog, map[string]interface{}{"id": 1}); got != "/dogs/1" {
+		t.Errorf("Builder.PathID() = %v, want %v", got, "/dogs/1")
+	}
+	if got := pb.Path("0", nil); got != "/named" {
+		t.Errorf("Builder.Path() = %v, want %v", got, "/named")
+	}
+	c := pb.Clone()
+	pb.Reset()
+	if _, err := pb.PathID(showDog, nil); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Builder.PathID() error = %v, want %v", err, ErrNotFound)
+	}
+	if got, _ := c.PathID(showDog, map[string]interface{}{"id": 1}); got != "/dogs/1" {
+		t.Errorf("Builder.PathID() on the clone = %v, want %v", got, "/dogs/1")
+	}
+}
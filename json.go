@@ -0,0 +1,67 @@
+package path
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// LoadJSON reads a JSON object of names and formats from r and
+// sets each of them as a named path. Eg:
+//
+//	{"show_dog": "/dogs/:id", "edit_dog": "/dogs/:id/edit"}
+//
+// If the JSON cannot be decoded the error is returned and no
+// paths are set.
+func (b *Builder) LoadJSON(r io.Reader) error {
+	var paths map[string]string
+	if err := json.NewDecoder(r).Decode(&paths); err != nil {
+		return err
+	}
+	b.SetAll(paths)
+	return nil
+}
+
+// ReloadJSON is like LoadJSON, but every path is replaced by
+// the paths in the JSON, the same as Replace. Each format is
+// checked like SetValid first, and if the JSON cannot be
+// decoded or any format is invalid the error is returned and
+// the existing paths are left unchanged. The paths are swapped
+// while holding the lock, so concurrent readers will see
+// either the old paths or the new ones, never a mix of both.
+// This makes it safe to reload paths while they are in use.
+func (b *Builder) ReloadJSON(r io.Reader) error {
+	b = b.table()
+	var paths map[string]string
+	if err := json.NewDecoder(r).Decode(&paths); err != nil {
+		return err
+	}
+	loaded := &Builder{ParamStyle: b.ParamStyle, Separator: b.Separator, CaseInsensitiveNames: b.CaseInsensitiveNames}
+	if err := loaded.setValid(paths); err != nil {
+		return err
+	}
+	b.m.Lock()
+	defer b.m.Unlock()
+	b.init()
+	b.paths = loaded.paths
+	return nil
+}
+
+// MarshalJSON encodes the names and formats of every path that
+// has been set as a JSON object, in the same form read by
+// LoadJSON. Options are not included.
+func (b *Builder) MarshalJSON() ([]byte, error) {
+	return json.Marshal(b.Snapshot())
+}
+
+// UnmarshalJSON sets each name and format in a JSON object as
+// a named path, the same as LoadJSON. Paths already set with
+// other names are kept. If the JSON cannot be decoded the
+// error is returned and no paths are set.
+func (b *Builder) UnmarshalJSON(data []byte) error {
+	var paths map[string]string
+	if err := json.Unmarshal(data, &paths); err != nil {
+		return err
+	}
+	b.SetAll(paths)
+	return nil
+}
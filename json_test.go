@@ -0,0 +1,129 @@
+package path
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestBuilder_LoadJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		json    string
+		want    map[string]string
+		wantErr bool
+	}{
+		{"empty object", `{}`, map[string]string{}, false},
+		{"paths", `{"show_dog": "/dogs/:id", "edit_dog": "/dogs/:id/edit"}`, map[string]string{
+			"show_dog": "/dogs/:id",
+			"edit_dog": "/dogs/:id/edit",
+		}, false},
+		{"malformed", `{"show_dog": `, map[string]string{}, true},
+		{"not an object", `["/dogs/:id"]`, map[string]string{}, true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var pb Builder
+			err := pb.LoadJSON(strings.NewReader(tc.json))
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("Builder.LoadJSON() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if got := len(pb.Names()); got != len(tc.want) {
+				t.Errorf("len(Builder.Names()) = %v, want %v", got, len(tc.want))
+			}
+			for name, want := range tc.want {
+				if got, _ := pb.Format(name); got != want {
+					t.Errorf("Builder.Format(%v) = %v, want %v", name, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestBuilder_ReloadJSON(t *testing.T) {
+	old := map[string]string{"show_dog": "/dogs/:id", "edit_dog": "/dogs/:id/edit"}
+	tests := []struct {
+		name    string
+		json    string
+		want    map[string]string
+		wantErr bool
+	}{
+		{"empty object", `{}`, map[string]string{}, false},
+		{"paths", `{"show_dog": "/v2/dogs/:id", "show_cat": "/cats/:id"}`, map[string]string{
+			"show_dog": "/v2/dogs/:id",
+			"show_cat": "/cats/:id",
+		}, false},
+		{"malformed", `{"show_dog": `, old, true},
+		{"invalid format", `{"show_dog": "/dogs/:id", "bad": "/files/*rest/edit"}`, old, true},
+		{"cycle", `{"a": "{b}/a", "b": "{a}/b"}`, old, true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var pb Builder
+			pb.SetAll(old)
+			err := pb.ReloadJSON(strings.NewReader(tc.json))
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("Builder.ReloadJSON() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if got := pb.Snapshot(); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("Builder.Snapshot() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+
+	// Formats are checked with the Builder's Separator
+	pb := Builder{Separator: "."}
+	if err := pb.ReloadJSON(strings.NewReader(`{"bad": "a.*b.c"}`)); err == nil {
+		t.Errorf("Builder.ReloadJSON() error = %v, want an error", err)
+	}
+	if pb.Has("bad") {
+		t.Errorf("Builder.Has() = %v, want %v", true, false)
+	}
+}
+
+func TestBuilder_MarshalJSON(t *testing.T) {
+	var pb Builder
+	got, err := json.Marshal(&pb)
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v, want %v", err, nil)
+	}
+	if want := `{}`; string(got) != want {
+		t.Errorf("json.Marshal() = %s, want %s", got, want)
+	}
+	pb.Set("show_dog", "/dogs/:id")
+	pb.Set("edit_dog", "/dogs/:id/edit")
+	got, err = json.Marshal(&pb)
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v, want %v", err, nil)
+	}
+	if want := `{"edit_dog":"/dogs/:id/edit","show_dog":"/dogs/:id"}`; string(got) != want {
+		t.Errorf("json.Marshal() = %s, want %s", got, want)
+	}
+}
+
+func TestBuilder_UnmarshalJSON(t *testing.T) {
+	var pb Builder
+	pb.Set("show_dog", "/dogs/:id")
+	err := json.Unmarshal([]byte(`{"edit_dog": "/dogs/:id/edit", "show_dog": "/dog/:id"}`), &pb)
+	if err != nil {
+		t.Fatalf("json.Unmarshal() error = %v, want %v", err, nil)
+	}
+	want := map[string]string{
+		"edit_dog": "/dogs/:id/edit",
+		"show_dog": "/dog/:id",
+	}
+	for name, want := range want {
+		if got, _ := pb.Format(name); got != want {
+			t.Errorf("Builder.Format(%v) = %v, want %v", name, got, want)
+		}
+	}
+
+	err = json.Unmarshal([]byte(`{"new_dog": `), &pb)
+	if err == nil {
+		t.Fatalf("json.Unmarshal() error = %v, want an error", err)
+	}
+	if got := len(pb.Names()); got != 2 {
+		t.Errorf("len(Builder.Names()) = %v, want %v", got, 2)
+	}
+}
@@ -0,0 +1,116 @@
+package path
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Match is the reverse of Path. It determines whether or not
+// the provided path, eg `/dogs/123/edit`, matches the format
+// of the named path, eg `/dogs/:id/edit`, and if so returns
+// the values of each param in the format, eg:
+//
+//	map[string]string{"id": "123"}
+//
+// For a path to match it must have the same number of
+// segments as the format, and each segment that isn't a param
+// must be equal. A catch-all param matches the remainder of
+// the path. Params in a segment with other text, eg
+// `:id.json`, match up to the text that follows them. Values
+// are unescaped and any query string in the path is ignored.
+func (b *Builder) Match(name, path string) (map[string]string, bool) {
+	p, ok := b.get(name, b.ParamStyle)
+	if !ok {
+		return nil, false
+	}
+	return p.match(path)
+}
+
+// MatchTyped is like Match, but the values of params with a
+// type set by SetTyped are converted to that type, eg an "int"
+// param is returned as an int. All other values are strings.
+func (b *Builder) MatchTyped(name, path string) (map[string]interface{}, bool) {
+	p, ok := b.get(name, b.ParamStyle)
+	if !ok {
+		return nil, false
+	}
+	params, ok := p.match(path)
+	if !ok {
+		return nil, false
+	}
+	return p.convert(params)
+}
+
+// MatchAny determines which named path, if any, the provided
+// path matches. See Match for details on how paths are
+// matched.
+//
+// When several named paths match, the most specific one is
+// returned, which is the path whose format has the most
+// segments that aren't params. Eg `/dogs/new` is preferred
+// over `/dogs/:id`. Ties are broken by name, in alphabetical
+// order.
+func (b *Builder) MatchAny(path string) (name string, params map[string]string, ok bool) {
+	b = b.table()
+	b.m.Lock()
+	type route struct {
+		name     string
+		p        *pattern
+		literals int
+	}
+	routes := make([]route, 0, len(b.paths))
+	for name := range b.paths {
+		p, _ := b.pattern(name, b.ParamStyle)
+		routes = append(routes, route{name, p, p.literals()})
+	}
+	b.m.Unlock()
+	sort.Slice(routes, func(i, j int) bool {
+		if routes[i].literals != routes[j].literals {
+			return routes[i].literals > routes[j].literals
+		}
+		return routes[i].name < routes[j].name
+	})
+	for _, r := range routes {
+		if params, ok := r.p.match(path); ok {
+			return r.name, params, true
+		}
+	}
+	return "", nil, false
+}
+
+// Verify checks that the named path can be built with params
+// and then matched back to the same params. Every param is
+// expected to fill a param in the path; query params are not
+// supported. An error is returned describing the first
+// problem found, such as a param that isn't in the format or
+// a value that doesn't survive the round trip, eg because it
+// contains a slash and RawPathParams is set.
+//
+// Verify is primarily intended to be used in tests to catch
+// mistakes in formats.
+func (b *Builder) Verify(name string, params map[string]interface{}) error {
+	cfg := b.config()
+	cfg.query = false
+	cfg.baseURL = ""
+	cfg.prefix = ""
+	cfg.slash = TrailingSlashAsIs
+	path, err := b.build(name, params, cfg)
+	if err != nil {
+		return err
+	}
+	got, ok := b.Match(name, path)
+	if !ok {
+		return fmt.Errorf("path: %q built %q which does not match its format", name, path)
+	}
+	for k, v := range params {
+		gv, ok := got[k]
+		if !ok {
+			return fmt.Errorf("path: %q has no param named %q", name, k)
+		}
+		v, _ = unwrapInQuery(v)
+		if want := cfg.str(v); gv != want {
+			return fmt.Errorf("path: %q param %q matched as %q, want %q", name, k, gv, want)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,208 @@
+package path
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBuilder_Match(t *testing.T) {
+	var pb Builder
+	pb.Set("edit_dog", "/dogs/:id/edit")
+	tests := []struct {
+		name   string
+		path   string
+		arg    string
+		want   map[string]string
+		wantOk bool
+	}{
+		{"match", "edit_dog", "/dogs/123/edit", map[string]string{"id": "123"}, true},
+		{"no match", "edit_dog", "/cats/123/edit", nil, false},
+		{"missing path", "fake_path", "/dogs/123/edit", nil, false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, ok := pb.Match(tc.path, tc.arg)
+			if ok != tc.wantOk {
+				t.Fatalf("Builder.Match() ok = %v, want %v", ok, tc.wantOk)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("Builder.Match() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+
+	pb = Builder{ParamStyle: BraceStyle}
+	pb.Set("edit_dog", "/dogs/{id}/edit")
+	got, ok := pb.Match("edit_dog", "/dogs/123/edit")
+	if want := map[string]string{"id": "123"}; !ok || !reflect.DeepEqual(got, want) {
+		t.Errorf("Builder.Match() = %v, %v, want %v, %v", got, ok, want, true)
+	}
+}
+
+func TestBuilder_MatchAny(t *testing.T) {
+	var pb Builder
+	pb.Set("show_dog", "/dogs/:id")
+	pb.Set("new_dog", "/dogs/new")
+	pb.Set("edit_dog", "/dogs/:id/edit")
+	pb.Set("a_show_dog", "/dogs/:dog_id")
+	pb.Set("files", "/files/*filepath")
+	tests := []struct {
+		name       string
+		arg        string
+		wantName   string
+		wantParams map[string]string
+		wantOk     bool
+	}{
+		{"literal preferred", "/dogs/new", "new_dog", map[string]string{}, true},
+		{"ties broken by name", "/dogs/123", "a_show_dog", map[string]string{"dog_id": "123"}, true},
+		{"more segments", "/dogs/123/edit", "edit_dog", map[string]string{"id": "123"}, true},
+		{"catch-all", "/files/a/b.txt", "files", map[string]string{"filepath": "a/b.txt"}, true},
+		{"no match", "/cats/123", "", nil, false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			name, params, ok := pb.MatchAny(tc.arg)
+			if ok != tc.wantOk {
+				t.Fatalf("Builder.MatchAny() ok = %v, want %v", ok, tc.wantOk)
+			}
+			if name != tc.wantName {
+				t.Errorf("Builder.MatchAny() name = %v, want %v", name, tc.wantName)
+			}
+			if !reflect.DeepEqual(params, tc.wantParams) {
+				t.Errorf("Builder.MatchAny() params = %v, want %v", params, tc.wantParams)
+			}
+		})
+	}
+}
+
+func TestBuilder_Verify(t *testing.T) {
+	pb := Builder{
+		BaseURL:       "https://example.com",
+		TrailingSlash: TrailingSlashAlways,
+	}
+	pb.Set("show_dog", "/dogs/:id")
+	pb.Set("show_file", "/files/*filepath")
+	pb.Set("show_post", "/posts/:slug")
+	tests := []struct {
+		name    string
+		path    string
+		params  map[string]interface{}
+		raw     bool
+		wantErr bool
+	}{
+		{"round trip", "show_dog", map[string]interface{}{"id": 123}, false, false},
+		{"escaped value", "show_post", map[string]interface{}{"slug": "a/b c"}, false, false},
+		{"catch-all", "show_file", map[string]interface{}{"filepath": "a/b.txt"}, false, false},
+		{"missing placeholder", "show_dog", map[string]interface{}{"id": 123, "dog_id": 123}, false, true},
+		{"value with slash and raw params", "show_post", map[string]interface{}{"slug": "a/b"}, true, true},
+		{"missing path", "fake_path", nil, false, true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			pb.RawPathParams = tc.raw
+			err := pb.Verify(tc.path, tc.params)
+			if (err != nil) != tc.wantErr {
+				t.Errorf("Builder.Verify() error = %v, wantErr %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func Test_match(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+		path   string
+		want   map[string]string
+		wantOk bool
+	}{
+		{"no params", "/dogs", "/dogs", map[string]string{}, true},
+		{"one param", "/dogs/:id", "/dogs/123", map[string]string{"id": "123"}, true},
+		{"many params", "/dogs/:id/toys/:toy_id", "/dogs/1/toys/2", map[string]string{"id": "1", "toy_id": "2"}, true},
+		{"escaped value", "/posts/:slug", "/posts/a%2Fb%20c", map[string]string{"slug": "a/b c"}, true},
+		{"query is ignored", "/dogs/:id", "/dogs/123?age=12", map[string]string{"id": "123"}, true},
+		{"catch-all", "/files/*filepath", "/files/a/b/c.txt", map[string]string{"filepath": "a/b/c.txt"}, true},
+		{"catch-all escaped", "/files/*filepath", "/files/a%20b/c%3Fd%23e", map[string]string{"filepath": "a b/c?d#e"}, true},
+		{"catch-all invalid escape", "/files/*filepath", "/files/a%zz", nil, false},
+		{"repeated param", "/a/:id/b/:id", "/a/1/b/1", map[string]string{"id": "1"}, true},
+		{"repeated param mismatch", "/a/:id/b/:id", "/a/1/b/2", nil, false},
+		{"literal mismatch", "/dogs/:id/edit", "/dogs/123/show", nil, false},
+		{"too few segments", "/dogs/:id/edit", "/dogs/123", nil, false},
+		{"too many segments", "/dogs/:id", "/dogs/123/edit", nil, false},
+		{"catch-all too few segments", "/files/*filepath", "/files", nil, false},
+		{"catch-all not last", "/files/*filepath/edit", "/files/a/edit", nil, false},
+		{"invalid escape", "/posts/:slug", "/posts/%zz", nil, false},
+		{"optional present", "/posts/:id/:slug?", "/posts/1/hello", map[string]string{"id": "1", "slug": "hello"}, true},
+		{"optional omitted", "/posts/:id/:slug?", "/posts/1", map[string]string{"id": "1"}, true},
+		{"optional omitted in the middle", "/posts/:slug?/edit", "/posts/edit", map[string]string{}, true},
+		{"last optional omitted first", "/:a?/:b?", "/x", map[string]string{"a": "x"}, true},
+		{"too few segments with optional", "/posts/:id/:slug?", "/posts", nil, false},
+		{"suffix", "/reports/:id.csv", "/reports/42.csv", map[string]string{"id": "42"}, true},
+		{"suffix with dots in value", "/files/:name.tar.gz", "/files/v1.2.tar.gz", map[string]string{"name": "v1.2"}, true},
+		{"suffix mismatch", "/reports/:id.csv", "/reports/42.json", nil, false},
+		{"suffix without value", "/reports/:id.csv", "/reports/.csv", nil, false},
+		{"prefix", "/users/user-:id", "/users/user-7", map[string]string{"id": "7"}, true},
+		{"prefix mismatch", "/users/user-:id", "/users/7", nil, false},
+		{"params in a segment", "/items/:category-:id", "/items/toys-42", map[string]string{"category": "toys", "id": "42"}, true},
+		{"params in a segment mismatch", "/items/:category-:id", "/items/toys", nil, false},
+		{"adjacent params", "/items/:a:b", "/items/12", nil, false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, ok := compile(tc.format, ColonStyle, "").match(tc.path)
+			if ok != tc.wantOk {
+				t.Fatalf("match() ok = %v, want %v", ok, tc.wantOk)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("match() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestBuilder_MatchTyped(t *testing.T) {
+	var pb Builder
+	pb.SetTyped("show_dog", "/dogs/:id/:good/:name", map[string]string{"id": "int", "good": "bool", "name": "string"})
+	tests := []struct {
+		name   string
+		route  string
+		path   string
+		want   map[string]interface{}
+		wantOk bool
+	}{
+		{"converted", "show_dog", "/dogs/12/true/felix", map[string]interface{}{"id": 12, "good": true, "name": "felix"}, true},
+		{"invalid int", "show_dog", "/dogs/abc/true/felix", nil, false},
+		{"invalid bool", "show_dog", "/dogs/12/maybe/felix", nil, false},
+		{"missing path", "fake_path", "/dogs/12/true/felix", nil, false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, ok := pb.MatchTyped(tc.route, tc.path)
+			if ok != tc.wantOk {
+				t.Fatalf("Builder.MatchTyped() ok = %v, want %v", ok, tc.wantOk)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("Builder.MatchTyped() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+
+	// Match also fails when a typed param can't be converted
+	if _, ok := pb.Match("show_dog", "/dogs/abc/true/felix"); ok {
+		t.Errorf("Builder.Match() ok = %v, want %v", ok, false)
+	}
+	got, ok := pb.Match("show_dog", "/dogs/12/true/felix")
+	if want := map[string]string{"id": "12", "good": "true", "name": "felix"}; !ok || !reflect.DeepEqual(got, want) {
+		t.Errorf("Builder.Match() = %v, want %v", got, want)
+	}
+
+	defer func() {
+		if r := recover(); r == nil {
+			t.Fatalf("Builder.SetTyped() didn't panic")
+		}
+		if pb.Has("bad_type") {
+			t.Errorf("Builder.Has() = %v, want %v", true, false)
+		}
+	}()
+	pb.SetTyped("bad_type", "/bad/:id", map[string]string{"id": "Int"})
+}
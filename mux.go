@@ -0,0 +1,57 @@
+package path
+
+import (
+	"fmt"
+	"strings"
+)
+
+// MuxTemplate returns the format of the named path converted
+// into a gorilla/mux route template, eg `/dogs/:id` becomes
+// `/dogs/{id}` and the catch-all `/files/*filepath` becomes
+// `/files/{filepath:.*}`. This makes it possible to register
+// the same paths with mux, eg:
+//
+//	tpl, _ := pb.MuxTemplate("show_dog")
+//	r.Path(tpl).Name("show_dog")
+//
+// mux has no optional params, so an error is returned for
+// paths that use them, as well as a *NotFoundError if no path
+// exists with the name provided.
+func (b *Builder) MuxTemplate(name string) (string, error) {
+	p, ok := b.get(name, b.ParamStyle)
+	if !ok {
+		return "", &NotFoundError{Name: name}
+	}
+	if p.err != nil {
+		return "", p.err
+	}
+	var sb strings.Builder
+	for i, seg := range p.segments {
+		if i > 0 {
+			sb.WriteByte('/')
+		}
+		switch {
+		case seg.optional:
+			return "", fmt.Errorf("path: %q has the optional param %q, which mux does not support", name, seg.key)
+		case seg.catchAll:
+			sb.WriteString("{" + seg.key + ":.*}")
+		case seg.parts != nil:
+			for _, pt := range seg.parts {
+				writeMux(&sb, pt.key, pt.value)
+			}
+		default:
+			writeMux(&sb, seg.key, seg.value)
+		}
+	}
+	return sb.String(), nil
+}
+
+// writeMux writes the mux template for a param named key, or
+// value if key is empty.
+func writeMux(sb *strings.Builder, key, value string) {
+	if key == "" {
+		sb.WriteString(value)
+		return
+	}
+	sb.WriteString("{" + key + "}")
+}
@@ -0,0 +1,39 @@
+package path
+
+import "testing"
+
+func TestBuilder_MuxTemplate(t *testing.T) {
+	tests := []struct {
+		name    string
+		style   ParamStyle
+		format  string
+		want    string
+		wantErr bool
+	}{
+		{"no params", ColonStyle, "/dogs", "/dogs", false},
+		{"params", ColonStyle, "/dogs/:id/toys/:toy_id", "/dogs/{id}/toys/{toy_id}", false},
+		{"catch-all", ColonStyle, "/files/*filepath", "/files/{filepath:.*}", false},
+		{"params in a segment", ColonStyle, "/reports/:id.:format", "/reports/{id}.{format}", false},
+		{"inline default", ColonStyle, "/posts/:page=1", "/posts/{page}", false},
+		{"brace style", BraceStyle, "/dogs/{id}", "/dogs/{id}", false},
+		{"optional", ColonStyle, "/posts/:id/:slug?", "", true},
+		{"catch-all not last", ColonStyle, "/files/*filepath/edit", "", true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			pb := Builder{ParamStyle: tc.style}
+			pb.Set("test", tc.format)
+			got, err := pb.MuxTemplate("test")
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("Builder.MuxTemplate() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if got != tc.want {
+				t.Errorf("Builder.MuxTemplate() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+	var pb Builder
+	if _, err := pb.MuxTemplate("fake_path"); err == nil {
+		t.Errorf("Builder.MuxTemplate() error = %v, want an error", err)
+	}
+}
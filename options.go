@@ -0,0 +1,142 @@
+package path
+
+// Options are used to override the options of a Builder for a
+// single call to PathWithOptions. Each field overrides the
+// Builder option with the same name, and nil fields leave the
+// Builder's option as-is. Eg:
+//
+//	pb.PathWithOptions("show_dog", params, path.Options{
+//	  IgnoreExtraParams: path.Bool(true),
+//	})
+type Options struct {
+	IgnoreExtraParams *bool
+	RawPathParams     *bool
+	NestedQueryParams *bool
+	MissingParamMode  *MissingParamMode
+	TrailingSlash     *TrailingSlashMode
+}
+
+// Bool returns a pointer to v. It is intended to make it
+// easier to set the fields of Options.
+func Bool(v bool) *bool {
+	return &v
+}
+
+// PathWithOptions is like StrictPath, but any options set in
+// opts are used instead of the Builder's options. This is
+// useful when a single call needs different behavior, as the
+// Builder itself is left unchanged.
+func (b *Builder) PathWithOptions(name string, params map[string]interface{}, opts Options) (string, error) {
+	cfg := b.config()
+	cfg.opts = opts
+	return b.build(name, params, cfg)
+}
+
+// RouteOption is used to override the options of a Builder for
+// a single path when it is set. See Builder.Set for details.
+type RouteOption func(*Options)
+
+// IgnoreExtra returns a RouteOption that ignores extra params
+// rather than adding them to the query, regardless of the
+// Builder's IgnoreExtraParams option.
+func IgnoreExtra() RouteOption {
+	return func(o *Options) {
+		o.IgnoreExtraParams = Bool(true)
+	}
+}
+
+// WithOptions returns a RouteOption that overrides the
+// Builder's options with any set in opts. Options passed to
+// PathWithOptions take precedence over these.
+func WithOptions(opts Options) RouteOption {
+	return func(o *Options) {
+		o.merge(opts)
+	}
+}
+
+// merge sets every field of o that is set in other.
+func (o *Options) merge(other Options) {
+	if other.IgnoreExtraParams != nil {
+		o.IgnoreExtraParams = other.IgnoreExtraParams
+	}
+	if other.RawPathParams != nil {
+		o.RawPathParams = other.RawPathParams
+	}
+	if other.NestedQueryParams != nil {
+		o.NestedQueryParams = other.NestedQueryParams
+	}
+	if other.MissingParamMode != nil {
+		o.MissingParamMode = other.MissingParamMode
+	}
+	if other.TrailingSlash != nil {
+		o.TrailingSlash = other.TrailingSlash
+	}
+}
+
+func (o Options) apply(cfg *config) {
+	if o.IgnoreExtraParams != nil {
+		cfg.query = !*o.IgnoreExtraParams
+	}
+	if o.RawPathParams != nil {
+		cfg.raw = *o.RawPathParams
+	}
+	if o.NestedQueryParams != nil {
+		cfg.nested = *o.NestedQueryParams
+	}
+	if o.MissingParamMode != nil {
+		cfg.missing = *o.MissingParamMode
+	}
+	if o.TrailingSlash != nil {
+		cfg.slash = *o.TrailingSlash
+	}
+}
+
+// Option is used to set an option of a Builder created with
+// New. Each Option sets the Builder field with the same name,
+// so a Builder created without any options behaves the same
+// as the zero value.
+type Option func(*Builder)
+
+// WithIgnoreExtraParams returns an Option that sets
+// IgnoreExtraParams.
+func WithIgnoreExtraParams(ignore bool) Option {
+	return func(b *Builder) {
+		b.IgnoreExtraParams = ignore
+	}
+}
+
+// WithRequireAllParams returns an Option that sets
+// RequireAllParams.
+func WithRequireAllParams(require bool) Option {
+	return func(b *Builder) {
+		b.RequireAllParams = require
+	}
+}
+
+// WithParamStyle returns an Option that sets ParamStyle.
+func WithParamStyle(style ParamStyle) Option {
+	return func(b *Builder) {
+		b.ParamStyle = style
+	}
+}
+
+// WithBaseURL returns an Option that sets BaseURL.
+func WithBaseURL(baseURL string) Option {
+	return func(b *Builder) {
+		b.BaseURL = baseURL
+	}
+}
+
+// WithPrefix returns an Option that sets Prefix.
+func WithPrefix(prefix string) Option {
+	return func(b *Builder) {
+		b.Prefix = prefix
+	}
+}
+
+// WithFormatter returns an Option that sets Formatter.
+func WithFormatter(fn func(interface{}) string) Option {
+	return func(b *Builder) {
+		b.Formatter = fn
+	}
+}
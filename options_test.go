@@ -0,0 +1,112 @@
+package path
+
+import "testing"
+
+func TestBuilder_PathWithOptions(t *testing.T) {
+	empty := EmptySegment
+	always := TrailingSlashAlways
+	pb := Builder{RequireAllParams: true}
+	pb.Set("show_post", "/posts/:id/:slug")
+	tests := []struct {
+		name    string
+		params  map[string]interface{}
+		opts    Options
+		want    string
+		wantErr bool
+	}{
+		{"no overrides", map[string]interface{}{"id": 1, "slug": "a b", "page": 2}, Options{}, "/posts/1/a%20b?page=2", false},
+		{"builder defaults still apply", map[string]interface{}{"id": 1}, Options{}, "", true},
+		{"ignore extra params", map[string]interface{}{"id": 1, "slug": "a", "page": 2}, Options{IgnoreExtraParams: Bool(true)}, "/posts/1/a", false},
+		{"raw path params", map[string]interface{}{"id": 1, "slug": "a b"}, Options{RawPathParams: Bool(true)}, "/posts/1/a b", false},
+		{"nested query params", map[string]interface{}{"id": 1, "slug": "a", "f": map[string]int{"x": 1}}, Options{NestedQueryParams: Bool(true)}, "/posts/1/a?f%5Bx%5D=1", false},
+		{"missing param mode", map[string]interface{}{"id": 1}, Options{MissingParamMode: &empty}, "/posts/1/", false},
+		{"trailing slash", map[string]interface{}{"id": 1, "slug": "a"}, Options{TrailingSlash: &always}, "/posts/1/a/", false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := pb.PathWithOptions("show_post", tc.params, tc.opts)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("Builder.PathWithOptions() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if got != tc.want {
+				t.Errorf("Builder.PathWithOptions() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+	if pb.IgnoreExtraParams || pb.RawPathParams || pb.MissingParamMode != KeepPlaceholder {
+		t.Errorf("Builder.PathWithOptions() changed the Builder's options")
+	}
+}
+
+func TestBuilder_Set_routeOptions(t *testing.T) {
+	always := TrailingSlashAlways
+	var pb Builder
+	pb.Set("search", "/search", IgnoreExtra())
+	pb.Set("show_dog", "/dogs/:id", WithOptions(Options{TrailingSlash: &always}))
+	pb.Set("index", "/dogs")
+	tests := []struct {
+		name   string
+		path   string
+		params map[string]interface{}
+		want   string
+	}{
+		{"ignore extra", "search", map[string]interface{}{"q": "dogs"}, "/search"},
+		{"with options", "show_dog", map[string]interface{}{"id": 1, "page": 2}, "/dogs/1/?page=2"},
+		{"no options", "index", map[string]interface{}{"page": 2}, "/dogs?page=2"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := pb.StrictPath(tc.path, tc.params)
+			if err != nil {
+				t.Fatalf("Builder.StrictPath() error = %v, want %v", err, nil)
+			}
+			if got != tc.want {
+				t.Errorf("Builder.StrictPath() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+
+	// Options for a single call take precedence over the route
+	got, err := pb.PathWithOptions("search", map[string]interface{}{"q": "dogs"}, Options{IgnoreExtraParams: Bool(false)})
+	if err != nil {
+		t.Fatalf("Builder.PathWithOptions() error = %v, want %v", err, nil)
+	}
+	if want := "/search?q=dogs"; got != want {
+		t.Errorf("Builder.PathWithOptions() = %v, want %v", got, want)
+	}
+
+	// Route options are kept when the pattern is recompiled
+	pb.ParamStyle = BraceStyle
+	if got := pb.Path("search", map[string]interface{}{"q": "dogs"}); got != "/search" {
+		t.Errorf("Builder.Path() = %v, want %v", got, "/search")
+	}
+}
+
+func TestNew_options(t *testing.T) {
+	pb, err := New(map[string]string{"show_dog": "/dogs/{id}"},
+		WithIgnoreExtraParams(true),
+		WithRequireAllParams(true),
+		WithParamStyle(BraceStyle),
+		WithBaseURL("https://example.com"),
+		WithPrefix("/v1"),
+		WithFormatter(func(v interface{}) string { return "x" }),
+	)
+	if err != nil {
+		t.Fatalf("New() error = %v, want %v", err, nil)
+	}
+	got, err := pb.StrictPath("show_dog", map[string]interface{}{"id": 1, "page": 2})
+	if err != nil {
+		t.Fatalf("Builder.StrictPath() error = %v, want %v", err, nil)
+	}
+	if want := "https://example.com/v1/dogs/x"; got != want {
+		t.Errorf("Builder.StrictPath() = %v, want %v", got, want)
+	}
+	if _, err := pb.StrictPath("show_dog", nil); err == nil {
+		t.Errorf("Builder.StrictPath() error = %v, want an error", err)
+	}
+
+	pb = MustNew(nil, WithBaseURL("https://example.com"))
+	if pb.BaseURL != "https://example.com" || len(pb.Names()) != 0 {
+		t.Errorf("MustNew() = %+v, want a BaseURL and no paths", pb)
+	}
+}
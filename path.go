@@ -1,19 +1,176 @@
 package path
 
 import (
+	"encoding"
 	"errors"
 	"fmt"
 	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 // Potential errors you could receive from this package. These
 // are mostly self explanatory.
 var (
-	ErrNotFound = errors.New("path: no path could be found with the name provided")
+	ErrNotFound     = errors.New("path: no path could be found with the name provided")
+	ErrMissingParam = errors.New("path: no value was provided for a param in the path")
+	ErrCatchAllPos  = errors.New("path: a catch-all param must be the final segment of a path")
+	ErrInvalidBase  = errors.New("path: the BaseURL provided must include a host")
+	ErrDuplicate    = errors.New("path: a path already exists with the name provided")
+	ErrExtraParams  = errors.New("path: params were provided that are not in the path")
+	ErrCycle        = errors.New("path: a path references itself")
+	ErrLocale       = errors.New("path: the locale provided is not one of the Builder's Locales")
 )
 
+// NotFoundError is returned when no path could be found with
+// the name provided. It is treated as ErrNotFound by
+// errors.Is, so existing checks for ErrNotFound continue to
+// work.
+type NotFoundError struct {
+	Name string
+}
+
+func (e *NotFoundError) Error() string {
+	return fmt.Sprintf("path: no path could be found with the name %q", e.Name)
+}
+
+// Is reports whether target is ErrNotFound so that
+// errors.Is(err, ErrNotFound) works as expected.
+func (e *NotFoundError) Is(target error) bool {
+	return target == ErrNotFound
+}
+
+// MissingParamError is returned when RequireAllParams is set
+// and no value was provided for one of the params in a path.
+// Key is the name of the param that was missing, eg "id" for
+// the path `/dogs/:id`.
+type MissingParamError struct {
+	Key string
+}
+
+func (e *MissingParamError) Error() string {
+	return fmt.Sprintf("path: no value was provided for the param %q", e.Key)
+}
+
+// Is reports whether target is ErrMissingParam so that
+// errors.Is(err, ErrMissingParam) works as expected.
+func (e *MissingParamError) Is(target error) bool {
+	return target == ErrMissingParam
+}
+
+// ExtraParamsError is returned when DisallowExtraParams is set
+// and params were provided that don't fill a param in the
+// path. Keys are the names of those params, sorted.
+type ExtraParamsError struct {
+	Keys []string
+}
+
+func (e *ExtraParamsError) Error() string {
+	return fmt.Sprintf("path: params were provided that are not in the path: %s", strings.Join(e.Keys, ", "))
+}
+
+// Is reports whether target is ErrExtraParams so that
+// errors.Is(err, ErrExtraParams) works as expected.
+func (e *ExtraParamsError) Is(target error) bool {
+	return target == ErrExtraParams
+}
+
+// ParamStyle determines how params are written in the format
+// of a path.
+type ParamStyle int
+
+const (
+	// ColonStyle params are prefixed with a colon, eg
+	// `/dogs/:id`. Names start with a letter or underscore
+	// and contain only letters, digits, and underscores. This
+	// is the default.
+	ColonStyle ParamStyle = iota
+	// BraceStyle params are wrapped in braces, eg
+	// `/dogs/{id}`.
+	BraceStyle
+)
+
+// MissingParamMode determines what happens when a path is
+// built without a value for one of its params.
+type MissingParamMode int
+
+const (
+	// KeepPlaceholder leaves the param in the path as-is, eg
+	// `/dogs/:id`. This is the default.
+	KeepPlaceholder MissingParamMode = iota
+	// EmptySegment replaces the param with an empty string,
+	// eg `/dogs/`.
+	EmptySegment
+	// ReturnError causes StrictPath to return a
+	// *MissingParamError.
+	ReturnError
+)
+
+// TrailingSlashMode determines whether or not paths end with
+// a trailing slash.
+type TrailingSlashMode int
+
+const (
+	// TrailingSlashAsIs leaves paths as they are. This is the
+	// default.
+	TrailingSlashAsIs TrailingSlashMode = iota
+	// TrailingSlashAlways adds a trailing slash to paths that
+	// don't already have one, eg `/dogs` becomes `/dogs/`.
+	TrailingSlashAlways
+	// TrailingSlashNever removes a trailing slash from paths,
+	// eg `/dogs/` becomes `/dogs`. The root path `/` is left
+	// unchanged.
+	TrailingSlashNever
+)
+
+// Encoding determines how query params are escaped.
+type Encoding int
+
+const (
+	// FormEncoding escapes query params the same as
+	// url.Values, eg `q=hot+dogs&next=%2Fdogs`. This is the
+	// default.
+	FormEncoding Encoding = iota
+	// RFC3986Encoding escapes query params as described by RFC
+	// 3986, eg `q=hot%20dogs&next=/dogs`. Spaces are encoded
+	// as `%20`, and the characters `:`, `@`, `/`, and `?` are
+	// left as they are since they are allowed in a query.
+	// Characters that separate query params, such as `&`, `=`,
+	// and `+`, are still escaped.
+	RFC3986Encoding
+)
+
+// InQuery wraps a param value so that it is added as a query
+// param even when it also fills a param in the path. Normally
+// a param is used in one place or the other. Eg with the
+// format `/users/:id/posts` the params:
+//
+//	map[string]interface{}{"id": path.InQuery(1)}
+//
+// would result in `/users/1/posts?id=1`. Values that don't
+// fill a param in the path are unaffected by InQuery.
+func InQuery(v interface{}) interface{} {
+	return inQuery{v}
+}
+
+type inQuery struct {
+	v interface{}
+}
+
+// unwrapInQuery returns the value wrapped by InQuery, along
+// with whether or not v was wrapped.
+func unwrapInQuery(v interface{}) (interface{}, bool) {
+	if iq, ok := v.(inQuery); ok {
+		return iq.v, true
+	}
+	return v, false
+}
+
 // Builder is used to set and retrieve named paths.
 type Builder struct {
 	// Whether or not to turn additional parameters provided
@@ -31,18 +188,817 @@ type Builder struct {
 	// will be turned into URL query params.
 	IgnoreExtraParams bool
 
+	// Whether or not extra params should be treated as an
+	// error, eg because a typo in a key means it doesn't fill
+	// the param it was meant to. When this is true,
+	// StrictPath will return an *ExtraParamsError listing
+	// every param that doesn't fill a param in the path.
+	// Params wrapped with InQuery and defaults set with
+	// SetDefault are always allowed.
+	//
+	// This is the opposite of IgnoreExtraParams, so only one
+	// of them should be set. If both are, this takes
+	// precedence.
+	//
+	// The default value is false, meaning extra params are
+	// handled according to IgnoreExtraParams.
+	DisallowExtraParams bool
+
+	// Whether or not every param in a path must be provided a
+	// value. When this is true, StrictPath will return a
+	// *MissingParamError if a param like `:id` is left
+	// unfilled, and Path will return an empty string. This is
+	// the same as setting MissingParamMode to ReturnError, and
+	// takes precedence over MissingParamMode when set.
+	//
+	// The default value is false, meaning that params without
+	// a value are handled according to MissingParamMode.
+	RequireAllParams bool
+
+	// What to do with params that aren't provided a value
+	// when building a path. See the MissingParamMode constants
+	// for the available options.
+	//
+	// A param whose value is nil is treated the same as a
+	// param without a value, rather than being built as
+	// `<nil>`.
+	//
+	// The default value is KeepPlaceholder, meaning params
+	// without a value are left in the path as-is, eg
+	// `/dogs/:id`.
+	MissingParamMode MissingParamMode
+
+	// ParamResolver is used to look up the value of a param in
+	// the path that isn't in the params provided, eg to fill in
+	// the current tenant or locale without passing it to every
+	// call:
+	//
+	//   pb.ParamResolver = func(key string) (interface{}, bool) {
+	//     if key == "tenant" {
+	//       return currentTenant(), true
+	//     }
+	//     return nil, false
+	//   }
+	//
+	// Values it returns take precedence over defaults, and
+	// params it doesn't return a value for are handled
+	// according to MissingParamMode. Only params in the path
+	// are resolved, never query params. ParamResolver may be
+	// called concurrently, so it must be safe to do so.
+	//
+	// The default value is nil, meaning no params are resolved.
+	ParamResolver func(key string) (interface{}, bool)
+
+	// The syntax used for params in path formats. With
+	// ColonStyle a segment like `:id` is a param named `id`,
+	// while with BraceStyle the same param is written `{id}`.
+	//
+	// The default value is ColonStyle.
+	//
+	// ColonStyle param names start with a letter or
+	// underscore, are made up of letters, digits, and
+	// underscores, and end at the first other character.
+	// This means a segment can contain text and several
+	// params, eg `/reports/:id.csv` becomes `/reports/42.csv`
+	// and `/items/:category-:id` becomes `/items/toys-42`.
+	// With BraceStyle the same formats are written as
+	// `/reports/{id}.csv` and `/items/{category}-{id}`. A
+	// colon followed by a digit is text, so ports and times
+	// such as `localhost:8080` and `10:30` aren't params.
+	//
+	// Regardless of style, a segment like `*filepath` is a
+	// catch-all param named `filepath`. Its value may contain
+	// slashes and span several segments, eg `/files/*filepath`
+	// with the value `a/b.txt` becomes `/files/a/b.txt`. Each
+	// segment of the value is escaped like any other param, so
+	// `a/b c?` becomes `a/b%20c%3F`. A catch-all param must
+	// be the final segment of a path, otherwise StrictPath
+	// will return ErrCatchAllPos.
+	//
+	// Params can also be made optional with a trailing `?`,
+	// eg `/posts/:id/:slug?`. When no value is provided for an
+	// optional param, it is removed from the path along with
+	// its leading slash, eg `/posts/123`.
+	//
+	// Params can also have an inline default after an `=`, eg
+	// `/posts/:page=1` or `/posts/{page=1}`. The default is
+	// used like any other value when no value is provided for
+	// the param, so this builds `/posts/1`. With ColonStyle
+	// the default runs to the end of the segment.
+	ParamStyle ParamStyle
+
+	// The separator between the segments of a path, which can
+	// be changed to use paths for something other than URLs,
+	// eg the format `cache.dogs.:id` with the separator `.`
+	// builds `cache.dogs.123`. Params are still escaped as
+	// they would be in a URL, so a value with the separator in
+	// it is not escaped. Options that assume URLs, such as
+	// BaseURL, Prefix, TrailingSlash, and adding extra params
+	// to the query, may not make sense with other separators,
+	// so IgnoreExtraParams should usually be set as well.
+	//
+	// The default value is an empty string, meaning segments
+	// are separated by a slash.
+	Separator string
+
+	// An optional URL to prefix all paths with in order to
+	// build absolute URLs. Eg if this is set to
+	// `https://example.com` then the path `/dogs/:id` would
+	// be returned as `https://example.com/dogs/123`.
+	//
+	// The BaseURL must include a host, otherwise StrictPath
+	// will return an error. The default value is an empty
+	// string, meaning only the path is returned.
+	BaseURL string
+
+	// An optional prefix added to the start of every path,
+	// after the BaseURL and before any query params. Eg if
+	// this is set to `/v1` then the path `/dogs/:id` would be
+	// returned as `/v1/dogs/123`. Exactly one slash is kept
+	// between the BaseURL, Prefix, and path. Unlike a Group,
+	// changing the Prefix affects paths that have already been
+	// set.
+	//
+	// The default value is an empty string, meaning paths are
+	// not prefixed.
+	Prefix string
+
+	// The locales LocalePath accepts, eg `en` and `fr`. The
+	// locale is added after the Prefix and before the path, so
+	// `/dogs/:id` would be returned as `/fr/dogs/123`.
+	//
+	// The default value is nil, meaning LocalePath always
+	// returns an error.
+	Locales []string
+
+	// Whether or not to insert param values into the path
+	// without escaping them. By default values are escaped
+	// with url.PathEscape, so the value `a/b c` for the param
+	// `:slug` becomes `a%2Fb%20c`. Setting this to true is
+	// useful when the value is meant to be interpreted by
+	// something else, like a route pattern for another router.
+	//
+	// The slashes in catch-all params are never escaped, but
+	// the rest of their value is unless this is set. The
+	// default value is false, meaning param values are
+	// escaped.
+	RawPathParams bool
+
+	// An optional function used to format param values as
+	// strings, for both path and query params. This can be
+	// used to control how types like time.Time are rendered
+	// in URLs. The value of a slice query param is passed in
+	// one element at a time.
+	//
+	// The default value is nil, meaning integers are formatted
+	// without decimals, floats with strconv.FormatFloat using
+	// the 'g' format and the fewest digits needed, bools as
+	// true or false, values implementing
+	// encoding.TextMarshaler with MarshalText, and anything
+	// else with fmt.Sprintf("%v").
+	Formatter func(interface{}) string
+
+	// The layout used to format time.Time param values, as
+	// accepted by time.Time.Format. This is ignored when a
+	// Formatter is set.
+	//
+	// The default value is an empty string, meaning times are
+	// formatted with time.RFC3339.
+	TimeLayout string
+
+	// Whether or not query params with a map value should be
+	// flattened into bracketed keys. Eg if you pass the
+	// params:
+	//
+	//   map[string]interface{}{
+	//     "filter": map[string]interface{}{"status": "active"},
+	//   }
+	//
+	// and this option was set to true, the query would be
+	// `filter[status]=active`. Maps are flattened at any
+	// depth, but only maps with string keys are supported.
+	//
+	// The default value is false, meaning map values are
+	// formatted like any other value.
+	NestedQueryParams bool
+
+	// Whether or not each element of a slice or array query
+	// param should be encoded with its index. Eg with this
+	// set, the value []int{1, 2} with the key "ids" is
+	// encoded as `ids[0]=1&ids[1]=2` rather than
+	// `ids=1&ids=2`. Elements are always encoded in order.
+	//
+	// The default value is false, meaning the key is repeated
+	// for each element.
+	IndexedArrayQuery bool
+
+	// Whether or not to skip query params whose value is nil
+	// or formats as an empty string. Eg with this set, the
+	// params:
+	//
+	//   map[string]interface{}{"q": "", "page": 0}
+	//
+	// would result in the query `page=0` rather than
+	// `page=0&q=`. Zero values other than the empty string,
+	// such as 0 or false, are never skipped.
+	//
+	// The default value is false, meaning empty query params
+	// are kept, and query params whose value is nil are added
+	// with an empty value, eg `q=`.
+	OmitEmptyQuery bool
+
+	// An optional function used to transform the keys of
+	// query params before they are encoded, eg to convert
+	// `userId` into `user_id`. Param keys used to fill params
+	// in the path are unaffected.
+	//
+	// The default value is nil, meaning keys are used as-is.
+	QueryKeyFunc func(string) string
+
+	// Whether or not spaces in query params should be encoded
+	// as `%20` rather than `+`, eg `q=hot%20dogs`. This can
+	// matter when the exact encoding of a URL is important,
+	// such as when it is signed. A `+` in a value is always
+	// encoded as `%2B`.
+	//
+	// The default value is false, meaning spaces are encoded
+	// as `+` like url.Values does.
+	QuerySpaceAsPercent bool
+
+	// How query params are escaped. See the Encoding constants
+	// for the available options. Params in the path are always
+	// escaped like url.PathEscape unless RawPathParams is set.
+	//
+	// The default value is FormEncoding, meaning query params
+	// are escaped like url.Values does, subject to
+	// QuerySpaceAsPercent.
+	Encoding Encoding
+
+	// Whether or not query params with a bool value are added
+	// as bare flags. When this is true, a param whose value is
+	// true is added with just its key, eg `?active`, and a
+	// param whose value is false is left out.
+	//
+	// The default value is false, meaning bools are added like
+	// any other value, eg `?active=true`.
+	BareBoolFlags bool
+
+	// Whether or not a trailing slash should be added to or
+	// removed from built paths. This applies to the path
+	// after params are replaced, but before any query params
+	// are added. See the TrailingSlashMode constants for the
+	// available options.
+	//
+	// The default value is TrailingSlashAsIs, meaning paths
+	// are left as they are.
+	TrailingSlash TrailingSlashMode
+
+	// Whether or not to lowercase built paths, eg
+	// `/Dogs/:name` with the name `Rex` becomes `/dogs/rex`.
+	// This applies to the path after params are replaced, but
+	// not to the BaseURL, Prefix, or query. Only the letters A
+	// to Z are changed, and percent-encoded characters like
+	// `%2F` are left as they are so they aren't corrupted.
+	//
+	// The default value is false, meaning paths are left as
+	// they are.
+	Lowercase bool
+
+	// Whether or not to collapse runs of slashes in built
+	// paths into a single slash, eg `/dogs//edit` becomes
+	// `/dogs/edit`. This is useful when formats are put
+	// together by code. Like Lowercase, this applies to the
+	// path after params are replaced, so the `//` after the
+	// scheme of the BaseURL and any slashes in the query are
+	// left as they are.
+	//
+	// The default value is false, meaning slashes are left as
+	// they are.
+	CollapseSlashes bool
+
+	// Whether or not path names are case insensitive. When
+	// this is true, names are converted to lowercase both
+	// when paths are set and when they are looked up, so a
+	// path set as "Show_Dog" can be built with "show_dog".
+	// Formats and param keys are unaffected. This should be
+	// set before any paths are set.
+	//
+	// The default value is false, meaning names are case
+	// sensitive.
+	CaseInsensitiveNames bool
+
+	// Whether or not PathStruct reads query params from `url`
+	// struct tags, like github.com/google/go-querystring. When
+	// this is true, fields that fill a param in the path are
+	// used as usual, but any other field is only added to the
+	// query when it has a `url` tag. Eg:
+	//
+	//   type DogSearch struct {
+	//     ID    int    `path:"id"`
+	//     Sort  string `url:"sort,omitempty"`
+	//     Debug bool   `url:"-"`
+	//   }
+	//
+	// Fields tagged `url:"-"` are skipped, and the omitempty
+	// option skips fields with a zero value.
+	//
+	// The default value is false, meaning every field that
+	// isn't in the path is added to the query.
+	URLTags bool
+
+	// Whether or not to fill in matrix params, eg
+	// `/items;color=red/:id`. When this is true, each
+	// `;key=value` in a segment is filled with the value of
+	// the param key, falling back to the value in the format.
+	// A matrix param without a value in the format, eg
+	// `;color`, is left out when the param has no value.
+	// Matrix params are never added to the query.
+	//
+	// The default value is false, meaning matrix params are
+	// treated as plain text.
+	MatrixParams bool
+
+	// Now returns the current time, which VerifySigned uses to
+	// check whether or not a URL from SignedPathExpiring has
+	// expired. This is mostly useful in tests.
+	//
+	// The default value is nil, meaning time.Now is used.
+	Now func() time.Time
+
 	// unexported fields
-	m     sync.Mutex
-	once  sync.Once
-	paths map[string]string
+	parent   *Builder
+	m        sync.RWMutex
+	once     sync.Once
+	paths    map[string]*pattern
+	ids      map[int]*pattern
+	defaults map[string]interface{}
+	// validBase is the last BaseURL that checkBase found to
+	// be valid
+	validBase atomic.Value
+}
+
+// New returns a Builder with every name and format in routes
+// set as a named path. Each format is checked like SetValid,
+// and the error for the first invalid format, in order of
+// name, is returned. Any opts are applied before the paths are
+// set, eg:
+//
+//	pb, err := path.New(routes, path.WithBaseURL("https://example.com"))
+//
+// routes may be nil to create a Builder with only options.
+func New(routes map[string]string, opts ...Option) (*Builder, error) {
+	b := &Builder{}
+	for _, opt := range opts {
+		opt(b)
+	}
+	if err := b.setValid(routes); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// setValid sets every name and format in routes with SetValid,
+// in order by name, and returns the first error along with the
+// name of the path it was for. Paths can reference paths set
+// after them, so they are only checked for cycles once every
+// path has been set.
+func (b *Builder) setValid(routes map[string]string) error {
+	names := make([]string, 0, len(routes))
+	for name := range routes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := b.SetValid(name, routes[name]); err != nil {
+			return fmt.Errorf("path: %q: %w", name, err)
+		}
+	}
+	for _, name := range names {
+		if p, _ := b.get(name, b.ParamStyle); p.err != nil {
+			return fmt.Errorf("path: %q: %w", name, p.err)
+		}
+	}
+	return nil
 }
 
-// Set is used to set a named path.
-func (b *Builder) Set(name, format string) {
+// MustNew is like New but panics if any format is invalid. It
+// is intended for use during initialization, eg:
+//
+//	var paths = path.MustNew(map[string]string{
+//	  "show_dog": "/dogs/:id",
+//	})
+func MustNew(routes map[string]string, opts ...Option) *Builder {
+	b, err := New(routes, opts...)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+// Set is used to set a named path. Any opts provided override
+// the Builder's options whenever this path is built, eg:
+//
+//	pb.Set("search", "/search", path.IgnoreExtra())
+//
+// A format can reference another path by putting its name in
+// braces, eg:
+//
+//	pb.Set("dogs", "/dogs")
+//	pb.Set("show_dog", "{dogs}/:id")
+//
+// The reference is replaced by the other path's format each
+// time the path is used, so changing `dogs` also changes
+// `show_dog`. References take precedence over brace-style
+// params with the same name. A path that references itself,
+// directly or through other paths, fails with ErrCycle.
+func (b *Builder) Set(name, format string, opts ...RouteOption) {
+	b = b.table()
+	b.m.Lock()
+	defer b.m.Unlock()
+	b.init()
+	p := compile(format, b.ParamStyle, b.Separator)
+	for _, opt := range opts {
+		opt(&p.opts)
+	}
+	b.paths[b.normalize(name)] = p
+}
+
+// SetFunc is like Set, but the format of the path is the
+// result of calling fn each time the path is used. This is
+// useful when the format depends on something that isn't
+// known yet, such as runtime configuration. fn is called while
+// the Builder is locked, so it should be cheap, free of side
+// effects, and must not use the Builder.
+func (b *Builder) SetFunc(name string, fn func() string) {
+	b = b.table()
+	b.m.Lock()
+	defer b.m.Unlock()
+	b.init()
+	b.paths[b.normalize(name)] = &pattern{fn: fn}
+}
+
+// SetTyped is like Set, but the values of the params in types
+// are converted when the path is matched, eg:
+//
+//	pb.SetTyped("show_dog", "/dogs/:id", map[string]string{"id": "int"})
+//
+// Supported types are "int", "bool", and "string". A path
+// only matches when every typed param can be converted, so
+// `/dogs/abc` will not match the path above. Use MatchTyped
+// to get the converted values. SetTyped panics if a type isn't
+// supported, since the path could never be matched.
+func (b *Builder) SetTyped(name, format string, types map[string]string) {
+	for k, t := range types {
+		switch t {
+		case "int", "bool", "string":
+		default:
+			panic(fmt.Sprintf("path: SetTyped(%q): unsupported type %q for the param %q", name, t, k))
+		}
+	}
+	b = b.table()
+	b.m.Lock()
+	defer b.m.Unlock()
+	b.init()
+	p := compile(format, b.ParamStyle, b.Separator)
+	p.types = make(map[string]string, len(types))
+	for k, t := range types {
+		p.types[k] = t
+	}
+	b.paths[b.normalize(name)] = p
+}
+
+// SetValid is like Set, but the format is checked first and
+// an error describing the first invalid segment is returned
+// rather than setting the path. A format is invalid if a param
+// has an empty name or one that isn't made of letters,
+// digits, and underscores, eg `/dogs/:`, or if a catch-all
+// param isn't the final segment. This is useful to catch
+// mistakes in formats at startup.
+func (b *Builder) SetValid(name, format string) error {
+	b = b.table()
+	p := compile(format, b.ParamStyle, b.Separator)
+	if err := p.validate(); err != nil {
+		return err
+	}
+	b.m.Lock()
+	defer b.m.Unlock()
+	b.init()
+	b.paths[b.normalize(name)] = p
+	return nil
+}
+
+// SetUnique is like Set, but returns ErrDuplicate rather than
+// overwriting an existing path with the same name. This is
+// useful to catch two packages registering the same name.
+func (b *Builder) SetUnique(name, format string) error {
+	b = b.table()
+	b.m.Lock()
+	defer b.m.Unlock()
+	b.init()
+	name = b.normalize(name)
+	if _, ok := b.paths[name]; ok {
+		return ErrDuplicate
+	}
+	b.paths[name] = compile(format, b.ParamStyle, b.Separator)
+	return nil
+}
+
+// SetAll is used to set many named paths at once, where each
+// key in m is a name and each value is its format. Existing
+// paths with the same name are overwritten. All of the paths
+// are set while holding the lock, so concurrent readers will
+// never see only part of them. A nil or empty map is a no-op.
+func (b *Builder) SetAll(m map[string]string) {
+	b = b.table()
+	b.m.Lock()
+	defer b.m.Unlock()
+	b.init()
+	for name, format := range m {
+		b.paths[b.normalize(name)] = compile(format, b.ParamStyle, b.Separator)
+	}
+}
+
+// Delete is used to remove a named path. Deleting a name
+// that was never set is a no-op.
+func (b *Builder) Delete(name string) {
+	b = b.table()
+	b.m.Lock()
+	defer b.m.Unlock()
+	b.init()
+	delete(b.paths, b.normalize(name))
+}
+
+// Reset removes every named path and every path set with
+// SetID, leaving the Builder as if no paths had ever been set.
+// Options such as IgnoreExtraParams and defaults set with
+// SetDefault are left unchanged.
+func (b *Builder) Reset() {
+	b = b.table()
+	b.m.Lock()
+	defer b.m.Unlock()
+	b.init()
+	b.paths = make(map[string]*pattern)
+	b.ids = nil
+}
+
+// Snapshot returns the name and format of every path that has
+// been set. The map is a copy, so it can be modified and then
+// passed to Replace without affecting the Builder.
+func (b *Builder) Snapshot() map[string]string {
+	paths := make(map[string]string)
+	b.Walk(func(name, format string) error {
+		paths[name] = format
+		return nil
+	})
+	return paths
+}
+
+// Replace removes every named path and sets each name and
+// format in m in their place. Unlike SetAll, paths that aren't
+// in m are removed. The paths are swapped while holding the
+// lock, so concurrent readers will see either the old paths or
+// the new ones, never a mix of both.
+func (b *Builder) Replace(m map[string]string) {
+	b = b.table()
+	paths := make(map[string]*pattern, len(m))
+	for name, format := range m {
+		paths[b.normalize(name)] = compile(format, b.ParamStyle, b.Separator)
+	}
+	b.m.Lock()
+	defer b.m.Unlock()
+	b.init()
+	b.paths = paths
+}
+
+// Clone returns a copy of the Builder. The paths and options
+// of the clone are independent of the original, so setting a
+// path or changing an option on one has no effect on the
+// other.
+func (b *Builder) Clone() *Builder {
+	t := b.table()
+	t.m.Lock()
+	defer t.m.Unlock()
+	t.init()
+	c := b.options()
+	c.init()
+	for name, p := range t.paths {
+		c.paths[name] = p
+	}
+	if t.ids != nil {
+		c.ids = make(map[int]*pattern, len(t.ids))
+		for id, p := range t.ids {
+			c.ids[id] = p
+		}
+	}
+	for k, v := range t.defaults {
+		c.SetDefault(k, v)
+	}
+	return c
+}
+
+// options returns a new Builder with the same options as b,
+// but without any paths or defaults.
+func (b *Builder) options() *Builder {
+	return &Builder{
+		IgnoreExtraParams:    b.IgnoreExtraParams,
+		DisallowExtraParams:  b.DisallowExtraParams,
+		RequireAllParams:     b.RequireAllParams,
+		MissingParamMode:     b.MissingParamMode,
+		ParamResolver:        b.ParamResolver,
+		ParamStyle:           b.ParamStyle,
+		Separator:            b.Separator,
+		BaseURL:              b.BaseURL,
+		Prefix:               b.Prefix,
+		Locales:              append([]string(nil), b.Locales...),
+		RawPathParams:        b.RawPathParams,
+		Formatter:            b.Formatter,
+		TimeLayout:           b.TimeLayout,
+		NestedQueryParams:    b.NestedQueryParams,
+		IndexedArrayQuery:    b.IndexedArrayQuery,
+		OmitEmptyQuery:       b.OmitEmptyQuery,
+		QueryKeyFunc:         b.QueryKeyFunc,
+		QuerySpaceAsPercent:  b.QuerySpaceAsPercent,
+		Encoding:             b.Encoding,
+		BareBoolFlags:        b.BareBoolFlags,
+		TrailingSlash:        b.TrailingSlash,
+		Lowercase:            b.Lowercase,
+		CollapseSlashes:      b.CollapseSlashes,
+		CaseInsensitiveNames: b.CaseInsensitiveNames,
+		URLTags:              b.URLTags,
+		MatrixParams:         b.MatrixParams,
+		Now:                  b.Now,
+	}
+}
+
+// Merge copies all of the paths set on other into b. When
+// both Builders have a path with the same name, the path
+// already set on b takes precedence and is left unchanged.
+// Options such as IgnoreExtraParams are not copied.
+func (b *Builder) Merge(other *Builder) {
+	b, other = b.table(), other.table()
+	if b == other {
+		return
+	}
+	// Always lock the Builders in the same order, regardless
+	// of which is being merged into, to avoid deadlocks when
+	// two Builders are merged into each other concurrently.
+	first, second := b, other
+	if reflect.ValueOf(first).Pointer() > reflect.ValueOf(second).Pointer() {
+		first, second = second, first
+	}
+	first.m.Lock()
+	defer first.m.Unlock()
+	second.m.Lock()
+	defer second.m.Unlock()
+	b.init()
+	other.init()
+	for name, p := range other.paths {
+		name = b.normalize(name)
+		if _, ok := b.paths[name]; ok {
+			continue
+		}
+		b.paths[name] = p
+	}
+}
+
+// SetDefault sets a default value for the param key. Defaults
+// are used by every path, but a value provided when building a
+// path takes precedence. Eg with a default of "en" for the key
+// "locale", building `/home` with no params would return
+// `/home?locale=en`. Defaults follow the same rules as any
+// other param, so unless they fill a param in the path they
+// are subject to IgnoreExtraParams.
+func (b *Builder) SetDefault(key string, value interface{}) {
+	b = b.table()
+	b.m.Lock()
+	defer b.m.Unlock()
+	if b.defaults == nil {
+		b.defaults = make(map[string]interface{})
+	}
+	b.defaults[key] = value
+}
+
+// DeleteDefault removes the default value for the param key.
+// Deleting a key that has no default is a no-op.
+func (b *Builder) DeleteDefault(key string) {
+	b = b.table()
 	b.m.Lock()
 	defer b.m.Unlock()
+	delete(b.defaults, key)
+}
+
+// Has returns whether or not a path has been set with the
+// provided name.
+func (b *Builder) Has(name string) bool {
+	b = b.table()
+	b.m.RLock()
+	defer b.m.RUnlock()
+	_, ok := b.paths[b.normalize(name)]
+	return ok
+}
+
+// Format returns the raw, unexpanded format that was set for
+// the provided name, along with whether or not it was found.
+// Unlike Path, no params are ever substituted.
+func (b *Builder) Format(name string) (string, bool) {
+	p, ok := b.get(name, b.ParamStyle)
+	if !ok {
+		return "", false
+	}
+	return p.raw(), true
+}
+
+// Params returns the names of the params in the format of the
+// named path, in the order they appear, or a *NotFoundError
+// if no path exists with that name. Eg the format
+// `/dogs/:id/toys/:toy_id` would return []string{"id",
+// "toy_id"}. Params that appear more than once are only
+// included the first time.
+func (b *Builder) Params(name string) ([]string, error) {
+	p, ok := b.get(name, b.ParamStyle)
+	if !ok {
+		return nil, &NotFoundError{Name: name}
+	}
+	return p.names(), nil
+}
+
+// RoutesUsing returns the names of the paths whose format has
+// a param named param, sorted alphabetically. Only whole param
+// names are matched, so `id` matches `/dogs/:id` but not
+// `/dogs/:dog_id`.
+func (b *Builder) RoutesUsing(param string) []string {
+	b = b.table()
+	b.m.Lock()
+	defer b.m.Unlock()
+	b.init()
+	var names []string
+	for name := range b.paths {
+		if p, _ := b.pattern(name, b.ParamStyle); p.keys[param] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Names returns the names of all paths that have been set,
+// sorted alphabetically. The returned slice is a copy, so it
+// is safe to modify.
+func (b *Builder) Names() []string {
+	b = b.table()
+	b.m.RLock()
+	defer b.m.RUnlock()
+	names := make([]string, 0, len(b.paths))
+	for name := range b.paths {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Walk calls fn with the name and format of every path that
+// has been set, sorted by name. If fn returns an error Walk
+// stops and returns that error. The paths are copied before
+// fn is called, so fn may safely use the Builder, but paths
+// set while walking will not be visited.
+func (b *Builder) Walk(fn func(name, format string) error) error {
+	b = b.table()
+	b.m.Lock()
 	b.init()
-	b.paths[name] = format
+	names := make([]string, 0, len(b.paths))
+	formats := make(map[string]string, len(b.paths))
+	for name := range b.paths {
+		p, _ := b.pattern(name, b.ParamStyle)
+		names = append(names, name)
+		formats[name] = p.raw()
+	}
+	b.m.Unlock()
+	sort.Strings(names)
+	for _, name := range names {
+		if err := fn(name, formats[name]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// String returns every path that has been set, one per line
+// and sorted by name, in the format `name => format`, eg:
+//
+//	edit_dog => /dogs/:id/edit
+//	show_dog => /dogs/:id
+//
+// It is intended for debugging.
+func (b *Builder) String() string {
+	var sb strings.Builder
+	b.Walk(func(name, format string) error {
+		sb.WriteString(name)
+		sb.WriteString(" => ")
+		sb.WriteString(format)
+		sb.WriteByte('\n')
+		return nil
+	})
+	return sb.String()
 }
 
 // Path is used to retrieve a named path or return an empty
@@ -56,78 +1012,971 @@ func (b *Builder) Path(name string, params map[string]interface{}) string {
 	return ret
 }
 
+// Query builds a query string from params, without a leading
+// `?`, eg `age=12&name=felix`. Values are formatted and
+// encoded exactly as they would be for the query params of a
+// path. An empty or nil params map returns an empty string.
+func (b *Builder) Query(params map[string]interface{}) string {
+	return b.config().encode(params, nil)
+}
+
+// PathOrDefault is like Path, but returns fallback rather than
+// an empty string when the path cannot be built. Eg a fallback
+// of "#" is useful for links in templates.
+func (b *Builder) PathOrDefault(name string, params map[string]interface{}, fallback string) string {
+	ret, err := b.StrictPath(name, params)
+	if err != nil {
+		return fallback
+	}
+	return ret
+}
+
 // StrictPath is used to retrieve a named path or return an
-// error if no path exists with that name.
+// error if no path exists with that name. The error is a
+// *NotFoundError, which can be checked for with
+// errors.Is(err, ErrNotFound). If RequireAllParams
+// is set or the MissingParamMode is ReturnError, a
+// *MissingParamError is returned when any param in the path
+// is not provided a value.
 func (b *Builder) StrictPath(name string, params map[string]interface{}) (string, error) {
+	return b.build(name, params, b.config())
+}
+
+// PathE is the same as StrictPath, but its name makes it
+// clearer that an error is returned, as the counterpart to
+// Path. PathE is preferred in new code; StrictPath is kept for
+// compatibility.
+func (b *Builder) PathE(name string, params map[string]interface{}) (string, error) {
+	return b.StrictPath(name, params)
+}
+
+// PathOrdered is like StrictPath, but query params are added
+// in the order their keys are listed in order. Any remaining
+// query params follow, sorted by key. This is useful when a
+// canonical URL is needed, eg for a cache key. Keys in order
+// that aren't query params are ignored.
+func (b *Builder) PathOrdered(name string, params map[string]interface{}, order []string) (string, error) {
+	cfg := b.config()
+	cfg.order = order
+	return b.build(name, params, cfg)
+}
+
+// LocalePath is like StrictPath, but the path is prefixed
+// with the locale, eg `/fr/dogs/123` for the locale `fr`. This
+// allows one path to be used for every language a site is
+// served in. An error wrapping ErrLocale is returned if the
+// locale isn't one of the Builder's Locales.
+func (b *Builder) LocalePath(locale, name string, params map[string]interface{}) (string, error) {
+	known := false
+	for _, l := range b.Locales {
+		if l == locale {
+			known = true
+			break
+		}
+	}
+	if !known {
+		return "", fmt.Errorf("path: %q: %w", locale, ErrLocale)
+	}
+	cfg := b.config()
+	cfg.prefix = join(cfg.prefix, locale)
+	return b.build(name, params, cfg)
+}
+
+// PathWithValues is like StrictPath, but every value in q is
+// added to the query as it is, along with any params that
+// aren't in the path. This avoids converting a url.Values that
+// was built elsewhere into params first. Values in q are
+// added even if IgnoreExtraParams is set, and are sorted and
+// escaped the same as any other query params.
+func (b *Builder) PathWithValues(name string, params map[string]interface{}, q url.Values) (string, error) {
+	cfg := b.config()
+	cfg.values = q
+	return b.build(name, params, cfg)
+}
+
+// PathFiltered is like StrictPath, but only the params for
+// which keep returns true are used, eg to leave out zero
+// values. Rejected params are dropped entirely, so a param in
+// the path that is rejected is handled according to
+// MissingParamMode. keep is passed the value unwrapped if it
+// was wrapped with InQuery. Defaults are added after params
+// are filtered, so they are not passed to keep.
+func (b *Builder) PathFiltered(name string, params map[string]interface{}, keep func(key string, value interface{}) bool) (string, error) {
+	kept := make(map[string]interface{}, len(params))
+	for k, v := range params {
+		if uv, _ := unwrapInQuery(v); keep(k, uv) {
+			kept[k] = v
+		}
+	}
+	return b.StrictPath(name, kept)
+}
+
+// PathWithFragment is like StrictPath, but also appends the
+// fragment to the end of the path, after any query params.
+// Eg the fragment "installation" would result in a path like
+// `/docs/intro?x=1#installation`. The fragment is escaped as
+// needed, and an empty fragment adds nothing.
+func (b *Builder) PathWithFragment(name string, params map[string]interface{}, fragment string) (string, error) {
+	ret, err := b.StrictPath(name, params)
+	if err != nil {
+		return "", err
+	}
+	if fragment == "" {
+		return ret, nil
+	}
+	return ret + (&url.URL{Fragment: fragment}).String(), nil
+}
+
+// URL is like StrictPath, but the path is returned as a
+// *url.URL so that it can be modified further, eg to change
+// the scheme. The URL's String method returns the same path
+// that StrictPath would, including the BaseURL and any query
+// params.
+func (b *Builder) URL(name string, params map[string]interface{}) (*url.URL, error) {
+	ret, err := b.StrictPath(name, params)
+	if err != nil {
+		return nil, err
+	}
+	return url.Parse(ret)
+}
+
+// AbsoluteURL is like StrictPath, but the path is prefixed
+// with the scheme and host provided rather than the BaseURL,
+// eg `https://example.com/dogs/123`. This is useful when the
+// host is only known per request, eg from an *http.Request.
+// An error is returned if scheme is empty.
+func (b *Builder) AbsoluteURL(scheme, host, name string, params map[string]interface{}) (string, error) {
+	if scheme == "" {
+		return "", fmt.Errorf("path: AbsoluteURL requires a scheme")
+	}
+	cfg := b.config()
+	cfg.baseURL = ""
+	ret, err := b.build(name, params, cfg)
+	if err != nil {
+		return "", err
+	}
+	u, err := url.Parse(ret)
+	if err != nil {
+		return "", err
+	}
+	u.Scheme = scheme
+	u.Host = host
+	return u.String(), nil
+}
+
+// PathBytes is like StrictPath, but the path is returned as a
+// []byte. The path is built in a reusable buffer and then
+// copied, so it avoids allocating a string when the path is
+// only going to be written somewhere, eg to a log. The
+// returned slice is owned by the caller.
+func (b *Builder) PathBytes(name string, params map[string]interface{}) ([]byte, error) {
+	buf := bufPool.Get().(*[]byte)
+	defer bufPool.Put(buf)
+	ret, err := b.appendPath((*buf)[:0], name, params, b.config())
+	*buf = ret
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(nil), ret...), nil
+}
+
+// WritePath is like StrictPath, but the path is written to sb
+// rather than returned. This makes it possible to build a
+// large string, eg an HTML fragment with many links, with few
+// allocations. If the path cannot be built the error is
+// returned and nothing is written to sb.
+func (b *Builder) WritePath(sb *strings.Builder, name string, params map[string]interface{}) error {
+	buf := bufPool.Get().(*[]byte)
+	defer bufPool.Put(buf)
+	ret, err := b.appendPath((*buf)[:0], name, params, b.config())
+	*buf = ret
+	if err != nil {
+		return err
+	}
+	sb.Write(ret)
+	return nil
+}
+
+// MustPath is like StrictPath but panics if the path cannot
+// be built. It is intended for use during initialization, eg
+// when setting up templates, where a missing path is a bug.
+func (b *Builder) MustPath(name string, params map[string]interface{}) string {
+	ret, err := b.StrictPath(name, params)
+	if err != nil {
+		panic(fmt.Sprintf("path: MustPath(%q): %v", name, err))
+	}
+	return ret
+}
+
+// build looks up the named path and expands it using the
+// params and config provided.
+func (b *Builder) build(name string, params map[string]interface{}, cfg config) (string, error) {
+	buf := bufPool.Get().(*[]byte)
+	defer bufPool.Put(buf)
+	ret, err := b.appendPath((*buf)[:0], name, params, cfg)
+	*buf = ret
+	if err != nil {
+		return "", err
+	}
+	return string(ret), nil
+}
+
+// appendPath is like build, but the path is appended to dst.
+func (b *Builder) appendPath(dst []byte, name string, params map[string]interface{}, cfg config) ([]byte, error) {
+	p, ok := b.get(name, cfg.style)
+	if !ok {
+		return dst, &NotFoundError{Name: name}
+	}
+	return b.appendPattern(dst, p, params, cfg)
+}
+
+// appendPattern is like appendPath, but the pattern has
+// already been looked up.
+func (b *Builder) appendPattern(dst []byte, p *pattern, params map[string]interface{}, cfg config) ([]byte, error) {
+	p.opts.apply(&cfg)
+	cfg.opts.apply(&cfg)
+	if cfg.disallow {
+		if err := p.checkExtra(params, cfg); err != nil {
+			return dst, err
+		}
+	}
+	params = withResolved(p.keys, params, cfg.resolver)
+	params = b.withDefaults(params)
+	if cfg.baseURL != "" {
+		if err := b.checkBase(cfg.baseURL); err != nil {
+			return dst, err
+		}
+	}
+	head := cfg.baseURL
+	if cfg.prefix != "" {
+		head = join(head, cfg.prefix)
+	}
+	if head == "" {
+		return p.appendTo(dst, params, cfg)
+	}
+	// The head and path are joined with exactly one slash
+	// between them, unless the path is empty.
+	start := len(dst)
+	dst = append(dst, strings.TrimSuffix(head, "/")...)
+	mark := len(dst)
+	dst, err := p.appendTo(dst, params, cfg)
+	switch {
+	case err != nil:
+		return dst, err
+	case len(dst) == mark:
+		dst = append(dst[:start], head...)
+	case dst[mark] != '/':
+		dst = append(dst, 0)
+		copy(dst[mark+1:], dst[mark:])
+		dst[mark] = '/'
+	}
+	return dst, nil
+}
+
+// withDefaults returns params merged with any defaults set
+// with SetDefault, or params itself when there are none.
+func (b *Builder) withDefaults(params map[string]interface{}) map[string]interface{} {
+	b = b.table()
+	b.m.RLock()
+	defer b.m.RUnlock()
+	if len(b.defaults) == 0 {
+		return params
+	}
+	merged := make(map[string]interface{}, len(b.defaults)+len(params))
+	for k, v := range b.defaults {
+		merged[k] = v
+	}
+	for k, v := range params {
+		if _, ok := merged[k]; ok && v == nil {
+			// A nil value is treated as no value
+			continue
+		}
+		merged[k] = v
+	}
+	return merged
+}
+
+// withResolved returns params with a value from resolve added
+// for each of keys that isn't in params, or params itself when
+// there are none.
+func withResolved(keys map[string]bool, params map[string]interface{}, resolve func(string) (interface{}, bool)) map[string]interface{} {
+	if resolve == nil {
+		return params
+	}
+	var merged map[string]interface{}
+	for k := range keys {
+		if _, ok := lookup(params, k); ok {
+			continue
+		}
+		v, ok := resolve(k)
+		if !ok {
+			continue
+		}
+		if merged == nil {
+			merged = make(map[string]interface{}, len(params)+1)
+			for k, v := range params {
+				merged[k] = v
+			}
+		}
+		merged[k] = v
+	}
+	if merged == nil {
+		return params
+	}
+	return merged
+}
+
+// bufPool holds buffers used to build paths, so that only the
+// final string needs to be allocated.
+var bufPool = sync.Pool{
+	New: func() interface{} {
+		buf := make([]byte, 0, 128)
+		return &buf
+	},
+}
+
+// get is like pattern, but only takes a read lock unless the
+// pattern needs to be compiled. The Builder must not be
+// locked when get is called.
+func (b *Builder) get(name string, style ParamStyle) (*pattern, bool) {
+	b = b.table()
+	name = b.normalize(name)
+	b.m.RLock()
+	p, ok := b.paths[name]
+	var raw, format string
+	var err error
+	if ok {
+		raw = p.current()
+		format, err = b.resolve(raw, []string{name})
+	}
+	b.m.RUnlock()
+	if !ok {
+		return nil, false
+	}
+	if err != nil {
+		return &pattern{format: raw, err: err}, true
+	}
+	if !p.stale(style, b.separator(), raw, format) {
+		return p, true
+	}
 	b.m.Lock()
-	b.m.Unlock()
-	path, ok := b.paths[name]
+	defer b.m.Unlock()
+	if b.paths[name] != p {
+		// The path was set again while unlocked
+		return b.pattern(name, style)
+	}
+	return b.recompile(name, p, raw, format, style), true
+}
+
+// pattern returns the compiled pattern for the named path.
+// Patterns are compiled when they are set, so if the
+// ParamStyle has changed since then the pattern is compiled
+// again with the new style. Paths set with SetFunc are
+// compiled again whenever their function returns a new
+// format, and paths that reference other paths whenever one
+// of those paths changes. The write lock must be held.
+func (b *Builder) pattern(name string, style ParamStyle) (*pattern, bool) {
+	name = b.normalize(name)
+	p, ok := b.paths[name]
 	if !ok {
-		return "", ErrNotFound
+		return nil, false
+	}
+	raw := p.current()
+	format, err := b.resolve(raw, []string{name})
+	if err != nil {
+		return &pattern{format: raw, err: err}, true
+	}
+	if p.stale(style, b.separator(), raw, format) {
+		p = b.recompile(name, p, raw, format, style)
+	}
+	return p, true
+}
+
+// recompile replaces the pattern for the named path with one
+// compiled from format, keeping everything that was set along
+// with the original. raw is the format before references to
+// other paths were inlined. The write lock must be held.
+func (b *Builder) recompile(name string, p *pattern, raw, format string, style ParamStyle) *pattern {
+	c := compile(format, style, b.Separator)
+	if raw != format {
+		c.src = raw
+	}
+	c.fn, c.opts, c.types = p.fn, p.opts, p.types
+	b.paths[name] = c
+	return c
+}
+
+// resolve returns format with every reference to another path,
+// eg `{dogs}`, replaced by the format of that path. seen are
+// the names of the paths being resolved, which are used to
+// detect cycles. Text in braces that doesn't name a path is
+// left as is. The read or write lock must be held.
+func (b *Builder) resolve(format string, seen []string) (string, error) {
+	if strings.IndexByte(format, '{') < 0 {
+		return format, nil
+	}
+	var buf []byte
+	last := 0
+	for i := 0; i < len(format); i++ {
+		if format[i] != '{' {
+			continue
+		}
+		j := strings.IndexByte(format[i:], '}')
+		if j < 0 {
+			break
+		}
+		name := b.normalize(format[i+1 : i+j])
+		p, ok := b.paths[name]
+		if !ok {
+			continue
+		}
+		for _, s := range seen {
+			if s == name {
+				return format, fmt.Errorf("path: %s -> %s: %w", strings.Join(seen, " -> "), name, ErrCycle)
+			}
+		}
+		ref, err := b.resolve(p.current(), append(seen, name))
+		if err != nil {
+			return format, err
+		}
+		buf = append(buf, format[last:i]...)
+		buf = append(buf, ref...)
+		last = i + j + 1
+		i = last - 1
+	}
+	if buf == nil {
+		return format, nil
 	}
-	return replace(path, params, !b.IgnoreExtraParams), nil
+	return string(append(buf, format[last:]...)), nil
+}
+
+// separator returns the separator between segments, which is
+// a slash unless Separator is set.
+func (b *Builder) separator() string {
+	if b.Separator == "" {
+		return "/"
+	}
+	return b.Separator
+}
+
+// normalize returns the name used to store the named path,
+// which is lowercase when CaseInsensitiveNames is set.
+func (b *Builder) normalize(name string) string {
+	if b.CaseInsensitiveNames {
+		return strings.ToLower(name)
+	}
+	return name
+}
+
+// table returns the Builder that holds the paths and defaults
+// used by b. This is the Builder that b was created from by
+// Middleware, or b itself.
+func (b *Builder) table() *Builder {
+	if b.parent != nil {
+		return b.parent
+	}
+	return b
 }
 
 func (b *Builder) init() {
 	b.once.Do(func() {
-		b.paths = make(map[string]string)
+		b.paths = make(map[string]*pattern)
 	})
 }
 
-func replace(path string, params map[string]interface{}, query bool) string {
-	if params == nil {
-		return path
+// config holds the Builder settings used when expanding a
+// path.
+type config struct {
+	// query turns extra params into URL query params
+	query bool
+	// disallow makes extra params an error
+	disallow bool
+	// missing determines how params without a value are
+	// handled
+	missing MissingParamMode
+	// resolver looks up params that weren't provided
+	resolver func(string) (interface{}, bool)
+	// style is the syntax params are written in
+	style ParamStyle
+	// sep is the separator between segments
+	sep string
+	// baseURL is prefixed to the path when not empty
+	baseURL string
+	// prefix is added between the baseURL and the path
+	prefix string
+	// matrix fills in matrix params
+	matrix bool
+	// order is the order query params are encoded in
+	order []string
+	// raw disables escaping path param values
+	raw bool
+	// formatter formats values as strings when not nil
+	formatter func(interface{}) string
+	// timeLayout is the layout used to format times
+	timeLayout string
+	// nested flattens map query params into bracketed keys
+	nested bool
+	// indexed adds the index to the keys of slice query params
+	indexed bool
+	// omitEmpty skips nil and empty query param values
+	omitEmpty bool
+	// queryKey transforms query param keys when not nil
+	queryKey func(string) string
+	// spacePercent encodes spaces in the query as %20
+	spacePercent bool
+	// encoding is how the query is escaped
+	encoding Encoding
+	// bareBools adds bools to the query as bare flags
+	bareBools bool
+	// values are added to the query as they are, regardless
+	// of query
+	values url.Values
+	// opts override the options of the path being built,
+	// including any set with Set
+	opts Options
+	// slash determines how trailing slashes are handled
+	slash TrailingSlashMode
+	// lower lowercases the path
+	lower bool
+	// collapse collapses runs of slashes in the path
+	collapse bool
+}
+
+func (b *Builder) config() config {
+	cfg := config{
+		query:        !b.IgnoreExtraParams,
+		disallow:     b.DisallowExtraParams,
+		missing:      b.MissingParamMode,
+		resolver:     b.ParamResolver,
+		style:        b.ParamStyle,
+		sep:          b.Separator,
+		baseURL:      b.BaseURL,
+		prefix:       b.Prefix,
+		matrix:       b.MatrixParams,
+		raw:          b.RawPathParams,
+		formatter:    b.Formatter,
+		timeLayout:   b.TimeLayout,
+		nested:       b.NestedQueryParams,
+		indexed:      b.IndexedArrayQuery,
+		omitEmpty:    b.OmitEmptyQuery,
+		queryKey:     b.QueryKeyFunc,
+		spacePercent: b.QuerySpaceAsPercent,
+		encoding:     b.Encoding,
+		bareBools:    b.BareBoolFlags,
+		slash:        b.TrailingSlash,
+		lower:        b.Lowercase,
+		collapse:     b.CollapseSlashes,
 	}
-	pieces := strings.Split(path, "/")
-	fillVals := make(map[string]interface{})
-	// Default values are the key - eg :id => :id by default
-	// unless we provide a new value for it.
-	for _, piece := range pieces {
-		k, err := key(piece)
-		if err == errInvalidKey {
-			continue
+	if b.RequireAllParams {
+		cfg.missing = ReturnError
+	}
+	return cfg
+}
+
+// str formats the param value as a string.
+func (c config) str(v interface{}) string {
+	if c.formatter != nil {
+		return c.formatter(v)
+	}
+	if t, ok := v.(time.Time); ok {
+		if c.timeLayout == "" {
+			return t.Format(time.RFC3339)
+		}
+		return t.Format(c.timeLayout)
+	}
+	if m, ok := v.(encoding.TextMarshaler); ok {
+		if text, err := m.MarshalText(); err == nil {
+			return string(text)
 		}
-		fillVals[k] = piece
 	}
-	// Overwrite defaults values where the param is provided
+	return formatValue(v)
+}
+
+// formatValue is the default formatter for param values.
+// Integers are formatted without decimals, floats with the
+// fewest digits needed to represent them, and bools as true
+// or false. Anything else is formatted with fmt.Sprintf.
+func formatValue(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case bool:
+		return strconv.FormatBool(v)
+	case int:
+		return strconv.Itoa(v)
+	case int8:
+		return strconv.FormatInt(int64(v), 10)
+	case int16:
+		return strconv.FormatInt(int64(v), 10)
+	case int32:
+		return strconv.FormatInt(int64(v), 10)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case uint:
+		return strconv.FormatUint(uint64(v), 10)
+	case uint8:
+		return strconv.FormatUint(uint64(v), 10)
+	case uint16:
+		return strconv.FormatUint(uint64(v), 10)
+	case uint32:
+		return strconv.FormatUint(uint64(v), 10)
+	case uint64:
+		return strconv.FormatUint(v, 10)
+	case float32:
+		return strconv.FormatFloat(float64(v), 'g', -1, 32)
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	}
+	return fmt.Sprintf("%v", v)
+}
+
+// Expand builds a path from format without setting it on a
+// Builder, eg:
+//
+//	path.Expand("/dogs/:id", map[string]interface{}{"id": 123}, false)
+//
+// returns `/dogs/123`. When query is true, params that aren't
+// in the format are added as query params. Expand uses the
+// same defaults as a zero Builder, and returns an empty string
+// if the format is invalid.
+func Expand(format string, params map[string]interface{}, query bool) string {
+	ret, err := replace(format, params, config{query: query})
+	if err != nil {
+		return ""
+	}
+	return ret
+}
+
+func replace(path string, params map[string]interface{}, cfg config) (string, error) {
+	return compile(path, cfg.style, cfg.sep).expand(params, cfg)
+}
+
+// encode builds a query string from params, skipping any keys
+// in skip unless their value is wrapped with InQuery.
+func (c config) encode(params map[string]interface{}, skip map[string]bool) string {
+	q := c.collect(params, skip)
+	defer q.release()
+	return string(q.appendTo(nil))
+}
+
+// collect gathers the query params in params, skipping any
+// keys in skip unless their value is wrapped with InQuery. The
+// query should be released once it is no longer needed.
+func (c config) collect(params map[string]interface{}, skip map[string]bool) *query {
+	q := queryPool.Get().(*query)
+	q.order = c.order
+	q.mode = c.queryMode()
 	for k, v := range params {
-		fillVals[k] = v
-	}
-	// Build the final path's pieces, deleting keys we use
-	// so we can keep track for URL query params
-	var ret []string
-	for _, piece := range pieces {
-		k, err := key(piece)
-		if err == errInvalidKey {
-			ret = append(ret, piece)
+		v, inQuery := unwrapInQuery(v)
+		if skip[k] && !inQuery {
 			continue
 		}
-		ret = append(ret, fmt.Sprintf("%v", fillVals[k]))
-		delete(fillVals, k)
+		if c.queryKey != nil {
+			k = c.queryKey(k)
+		}
+		c.addQuery(q, k, v)
+	}
+	for k, vs := range c.values {
+		for _, v := range vs {
+			q.pairs = append(q.pairs, queryPair{k: k, v: v})
+		}
 	}
-	if !query {
-		return strings.Join(ret, "/")
+	return q
+}
+
+// addQuery adds the value to the query. Slices and arrays are
+// added one element at a time, eg the value []string{"a", "b"}
+// with the key "tag" is encoded as `tag=a&tag=b`. When nested
+// is set, maps with string keys are flattened into bracketed
+// keys, eg `filter[status]=a`.
+func (c config) addQuery(q *query, k string, v interface{}) {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < rv.Len(); i++ {
+			if !c.indexed {
+				c.addValue(q, k, rv.Index(i).Interface())
+				continue
+			}
+			n := len(q.pairs)
+			c.addQuery(q, k+"["+strconv.Itoa(i)+"]", rv.Index(i).Interface())
+			for j := n; j < len(q.pairs); j++ {
+				// Keep the elements in order, even past ids[9]
+				q.pairs[j].base, q.pairs[j].idx = k, i
+			}
+		}
+		return
+	case reflect.Map:
+		if c.nested && rv.Type().Key().Kind() == reflect.String {
+			for _, mk := range rv.MapKeys() {
+				c.addQuery(q, k+"["+mk.String()+"]", rv.MapIndex(mk).Interface())
+			}
+			return
+		}
+	}
+	c.addValue(q, k, v)
+}
+
+// addValue formats and adds a single value to the query,
+// unless omitEmpty is set and the value is nil or formats as
+// an empty string.
+func (c config) addValue(q *query, k string, v interface{}) {
+	if v == nil {
+		if !c.omitEmpty {
+			q.pairs = append(q.pairs, queryPair{k: k})
+		}
+		return
 	}
-	qv := make(url.Values)
-	for k, v := range fillVals {
-		qv.Set(k, fmt.Sprintf("%v", v))
+	if b, ok := v.(bool); ok && c.bareBools {
+		if b {
+			q.pairs = append(q.pairs, queryPair{k: k, bare: true})
+		}
+		return
 	}
-	if len(qv) > 0 {
-		return strings.Join(ret, "/") + "?" + qv.Encode()
+	s := c.str(v)
+	if c.omitEmpty && s == "" {
+		return
 	}
-	return strings.Join(ret, "/")
+	q.pairs = append(q.pairs, queryPair{k: k, v: s})
 }
 
-var (
-	errInvalidKey = errors.New("path: invalid key")
+// query is a reusable list of query params. Queries are pooled
+// so that building a path with query params doesn't need to
+// allocate a url.Values map and its slices every time.
+type query struct {
+	pairs []queryPair
+	// order are the keys to encode first, in order. All other
+	// keys follow, sorted by key.
+	order []string
+	// mode is how keys and values are escaped
+	mode escapeMode
+}
+
+type queryPair struct {
+	k, v string
+	// bare is set for flags that are encoded without a value,
+	// eg `active`.
+	bare bool
+	// base and idx are set for the elements of slices encoded
+	// with IndexedArrayQuery, eg "ids" and 10 for `ids[10]`,
+	// so that they are sorted by index rather than by key.
+	base string
+	idx  int
+}
+
+// sortKey returns the key the pair is sorted by.
+func (pair queryPair) sortKey() string {
+	if pair.base != "" {
+		return pair.base
+	}
+	return pair.k
+}
+
+var queryPool = sync.Pool{
+	New: func() interface{} { return new(query) },
+}
+
+// release resets the query and returns it to the pool.
+func (q *query) release() {
+	q.pairs = q.pairs[:0]
+	q.order = nil
+	queryPool.Put(q)
+}
+
+// appendTo appends the encoded query to dst. It is like
+// url.Values.Encode, except that keys listed in order are
+// encoded first and in that order. All other keys follow,
+// sorted by key. Values with the same key keep the order they
+// were added in.
+func (q *query) appendTo(dst []byte) []byte {
+	sort.Stable(q)
+	for i, pair := range q.pairs {
+		if i > 0 {
+			dst = append(dst, '&')
+		}
+		dst = appendEscaped(dst, pair.k, q.mode)
+		if pair.bare {
+			continue
+		}
+		dst = append(dst, '=')
+		dst = appendEscaped(dst, pair.v, q.mode)
+	}
+	return dst
+}
+
+// queryMode returns how query params are escaped.
+func (c config) queryMode() escapeMode {
+	switch {
+	case c.encoding == RFC3986Encoding:
+		return escapeRFC3986
+	case c.spacePercent:
+		return escapeFormPercent
+	}
+	return escapeForm
+}
+
+// escapeMode determines which characters appendEscaped
+// escapes.
+type escapeMode int
+
+const (
+	// escapePath escapes a path segment like url.PathEscape.
+	escapePath escapeMode = iota
+	// escapeForm escapes a query key or value like
+	// url.QueryEscape, with spaces encoded as +.
+	escapeForm
+	// escapeFormPercent is like escapeForm, but spaces are
+	// encoded as %20.
+	escapeFormPercent
+	// escapeRFC3986 escapes a query key or value as described
+	// by RFC 3986.
+	escapeRFC3986
 )
 
-func key(piece string) (string, error) {
-	if len(piece) == 0 {
-		return "", errInvalidKey
+// escapes returns whether or not the character c is escaped.
+// Unreserved characters are never escaped, and all others are
+// unless they are allowed by the mode.
+func (m escapeMode) escapes(c byte) bool {
+	if 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' {
+		return false
+	}
+	switch c {
+	case '-', '.', '_', '~':
+		return false
+	case '$', '&', '+', '=':
+		return m != escapePath
+	case ':', '@':
+		return m == escapeForm || m == escapeFormPercent
+	case '/', '?':
+		return m != escapeRFC3986
+	}
+	return true
+}
+
+// appendEscaped appends s to dst, percent-encoding each
+// character that the mode escapes. Both paths and queries are
+// escaped with it so that they are escaped consistently.
+func appendEscaped(dst []byte, s string, mode escapeMode) []byte {
+	const hex = "0123456789ABCDEF"
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == ' ' && mode == escapeForm:
+			dst = append(dst, '+')
+		case mode.escapes(c):
+			dst = append(dst, '%', hex[c>>4], hex[c&15])
+		default:
+			dst = append(dst, c)
+		}
 	}
-	if piece[0] != ':' {
-		return "", errInvalidKey
+	return dst
+}
+
+// rank returns the position of k in order, or len(order) if
+// it isn't present.
+func (q *query) rank(k string) int {
+	for i, o := range q.order {
+		if o == k {
+			return i
+		}
 	}
-	return piece[1:], nil
+	return len(q.order)
+}
+
+func (q *query) Len() int      { return len(q.pairs) }
+func (q *query) Swap(i, j int) { q.pairs[i], q.pairs[j] = q.pairs[j], q.pairs[i] }
+func (q *query) Less(i, j int) bool {
+	a, b := q.pairs[i], q.pairs[j]
+	ka, kb := a.sortKey(), b.sortKey()
+	if len(q.order) > 0 {
+		ri, rj := q.rank(ka), q.rank(kb)
+		if ri != rj {
+			return ri < rj
+		}
+	}
+	if ka != kb {
+		return ka < kb
+	}
+	if a.idx != b.idx {
+		return a.idx < b.idx
+	}
+	return a.k < b.k
+}
+
+// appendTrailingSlash adds or removes a single trailing slash
+// from the path that starts at dst[start] according to the
+// mode.
+func appendTrailingSlash(dst []byte, start int, mode TrailingSlashMode) []byte {
+	n := len(dst) - start
+	switch mode {
+	case TrailingSlashAlways:
+		if n == 0 || dst[len(dst)-1] != '/' {
+			return append(dst, '/')
+		}
+	case TrailingSlashNever:
+		if n > 1 && dst[len(dst)-1] == '/' {
+			return dst[:len(dst)-1]
+		}
+	}
+	return dst
+}
+
+// collapseSlashes replaces each run of slashes in the path
+// that starts at dst[start] with a single slash.
+func collapseSlashes(dst []byte, start int) []byte {
+	n := start
+	for i := start; i < len(dst); i++ {
+		if dst[i] == '/' && n > start && dst[n-1] == '/' {
+			continue
+		}
+		dst[n] = dst[i]
+		n++
+	}
+	return dst[:n]
+}
+
+// lowercase changes the letters A to Z in path to lowercase,
+// skipping percent-encoded characters like `%2F`.
+func lowercase(path []byte) {
+	for i := 0; i < len(path); i++ {
+		switch c := path[i]; {
+		case c == '%':
+			i += 2
+		case 'A' <= c && c <= 'Z':
+			path[i] = c + 'a' - 'A'
+		}
+	}
+}
+
+// checkBase returns an error if base isn't a valid BaseURL.
+// The last valid base is remembered, so that the BaseURL is
+// only parsed again when it changes.
+func (b *Builder) checkBase(base string) error {
+	if valid, _ := b.validBase.Load().(string); valid == base {
+		return nil
+	}
+	u, err := url.Parse(base)
+	if err != nil {
+		return err
+	}
+	if u.Host == "" {
+		return ErrInvalidBase
+	}
+	b.validBase.Store(base)
+	return nil
+}
+
+// join joins the two parts of a path, making sure there is
+// exactly one slash between them. If b is empty, a is
+// returned as-is.
+func join(a, b string) string {
+	if b == "" {
+		return a
+	}
+	return strings.TrimSuffix(a, "/") + "/" + strings.TrimPrefix(b, "/")
+}
+
+// isCatchAll returns whether or not the piece is a catch-all
+// param, eg `*filepath`.
+func isCatchAll(piece string) bool {
+	return len(piece) > 1 && piece[0] == '*'
 }
@@ -1,15 +1,61 @@
 package path
 
 import (
+	"errors"
 	"fmt"
 	"math/rand"
 	"net/url"
 	"reflect"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 )
 
+func TestNew(t *testing.T) {
+	pb, err := New(map[string]string{
+		"show_dog": "/dogs/:id",
+		"edit_dog": "/dogs/:id/edit",
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v, want %v", err, nil)
+	}
+	if got, want := pb.Path("edit_dog", map[string]interface{}{"id": 1}), "/dogs/1/edit"; got != want {
+		t.Errorf("Builder.Path() = %v, want %v", got, want)
+	}
+
+	_, err = New(map[string]string{
+		"a_ok":  "/ok",
+		"b_bad": "/dogs/:",
+		"c_bad": "/files/*rest/edit",
+	})
+	if err == nil || !strings.Contains(err.Error(), "b_bad") {
+		t.Errorf("New() error = %v, want an error for %v", err, "b_bad")
+	}
+	_, err = New(map[string]string{"bad": "/files/*rest/edit"})
+	if !errors.Is(err, ErrCatchAllPos) {
+		t.Errorf("New() error = %v, want %v", err, ErrCatchAllPos)
+	}
+	_, err = New(map[string]string{"a": "{b}/a", "b": "{a}/b"})
+	if !errors.Is(err, ErrCycle) {
+		t.Errorf("New() error = %v, want %v", err, ErrCycle)
+	}
+}
+
+func TestMustNew(t *testing.T) {
+	pb := MustNew(map[string]string{"show_dog": "/dogs/:id"})
+	if !pb.Has("show_dog") {
+		t.Errorf("Builder.Has() = %v, want %v", false, true)
+	}
+
+	defer func() {
+		if r := recover(); r == nil {
+			t.Fatalf("MustNew() didn't panic")
+		}
+	}()
+	MustNew(map[string]string{"bad": "/dogs/:"})
+}
+
 func TestBuilder_Set(t *testing.T) {
 	var pb Builder
 	var names []string
@@ -28,6 +74,589 @@ func TestBuilder_Set(t *testing.T) {
 	}
 }
 
+func TestBuilder_SetFunc(t *testing.T) {
+	var pb Builder
+	prefix := "/v1"
+	calls := 0
+	pb.SetFunc("show_dog", func() string {
+		calls++
+		return prefix + "/dogs/:id"
+	})
+	if calls != 0 {
+		t.Errorf("Builder.SetFunc() called fn %d times, want %d", calls, 0)
+	}
+	params := map[string]interface{}{"id": 123}
+	if got, want := pb.Path("show_dog", params), "/v1/dogs/123"; got != want {
+		t.Errorf("Builder.Path() = %v, want %v", got, want)
+	}
+	prefix = "/v2"
+	if got, want := pb.Path("show_dog", params), "/v2/dogs/123"; got != want {
+		t.Errorf("Builder.Path() = %v, want %v", got, want)
+	}
+	if got, want := pb.Path("show_dog", params), "/v2/dogs/123"; got != want {
+		t.Errorf("Builder.Path() = %v, want %v", got, want)
+	}
+	if got, _ := pb.Format("show_dog"); got != "/v2/dogs/:id" {
+		t.Errorf("Builder.Format() = %v, want %v", got, "/v2/dogs/:id")
+	}
+	if calls != 4 {
+		t.Errorf("fn calls = %d, want %d", calls, 4)
+	}
+	// Set should replace the func
+	pb.Set("show_dog", "/dogs/:id")
+	prefix = "/v3"
+	if got, want := pb.Path("show_dog", params), "/dogs/123"; got != want {
+		t.Errorf("Builder.Path() = %v, want %v", got, want)
+	}
+}
+
+func TestBuilder_Set_references(t *testing.T) {
+	var pb Builder
+	pb.Set("dogs", "/dogs")
+	pb.Set("show_dog", "{dogs}/:id")
+	pb.Set("dog_toys", "{show_dog}/toys")
+	params := map[string]interface{}{"id": 123}
+	if got, want := pb.Path("dog_toys", params), "/dogs/123/toys"; got != want {
+		t.Errorf("Builder.Path() = %v, want %v", got, want)
+	}
+	pb.Set("dogs", "/v2/dogs")
+	if got, want := pb.Path("dog_toys", params), "/v2/dogs/123/toys"; got != want {
+		t.Errorf("Builder.Path() = %v, want %v", got, want)
+	}
+	if got, want := pb.Path("show_dog", params), "/v2/dogs/123"; got != want {
+		t.Errorf("Builder.Path() = %v, want %v", got, want)
+	}
+	if got, _ := pb.Format("show_dog"); got != "{dogs}/:id" {
+		t.Errorf("Builder.Format() = %v, want %v", got, "{dogs}/:id")
+	}
+	if got, ok := pb.Match("dog_toys", "/v2/dogs/123/toys"); !ok || got["id"] != "123" {
+		t.Errorf("Builder.Match() = %v, %v, want %v", got, ok, map[string]string{"id": "123"})
+	}
+	if got, want := pb.Path("unknown", nil), ""; got != want {
+		t.Errorf("Builder.Path() = %v, want %v", got, want)
+	}
+	pb.Set("literal", "/{unknown}")
+	if got, want := pb.Path("literal", nil), "/{unknown}"; got != want {
+		t.Errorf("Builder.Path() = %v, want %v", got, want)
+	}
+
+	pb.Set("dogs", "{dog_toys}/dogs")
+	for _, name := range []string{"dogs", "show_dog", "dog_toys"} {
+		if _, err := pb.StrictPath(name, params); !errors.Is(err, ErrCycle) {
+			t.Errorf("Builder.StrictPath(%v) error = %v, want %v", name, err, ErrCycle)
+		}
+	}
+	pb.Set("dogs", "/dogs")
+	if got, want := pb.Path("dog_toys", params), "/dogs/123/toys"; got != want {
+		t.Errorf("Builder.Path() = %v, want %v", got, want)
+	}
+}
+
+func TestBuilder_SetValid(t *testing.T) {
+	tests := []struct {
+		name    string
+		style   ParamStyle
+		format  string
+		wantErr bool
+	}{
+		{"no params", ColonStyle, "/dogs", false},
+		{"params", ColonStyle, "/dogs/:id/toys/:toy_id", false},
+		{"catch-all", ColonStyle, "/files/*filepath", false},
+		{"optional", ColonStyle, "/posts/:id/:slug?", false},
+		{"params in a segment", ColonStyle, "/items/:category-:id.json", false},
+		{"inline default", ColonStyle, "/posts/:page=1", false},
+		{"brace params", BraceStyle, "/dogs/{id}/{slug?}", false},
+		{"empty name", ColonStyle, "/dogs/:", true},
+		{"empty name after text", ColonStyle, "/dogs/a:", true},
+		{"port", ColonStyle, "/hosts/localhost:8080/:id", false},
+		{"time", ColonStyle, "/at/10:30", false},
+		{"empty catch-all name", ColonStyle, "/files/*", true},
+		{"invalid catch-all name", ColonStyle, "/files/*file-path", true},
+		{"catch-all not last", ColonStyle, "/files/*filepath/edit", true},
+		{"empty brace name", BraceStyle, "/dogs/{}", true},
+		{"invalid brace name", BraceStyle, "/dogs/{dog id}", true},
+		{"unclosed brace", BraceStyle, "/dogs/{id", true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			pb := Builder{ParamStyle: tc.style}
+			err := pb.SetValid("test", tc.format)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("Builder.SetValid() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if got := pb.Has("test"); got == tc.wantErr {
+				t.Errorf("Builder.Has() = %v, want %v", got, !tc.wantErr)
+			}
+		})
+	}
+
+	var pb Builder
+	err := pb.SetValid("test", "/files/*filepath/edit")
+	if !errors.Is(err, ErrCatchAllPos) {
+		t.Errorf("Builder.SetValid() error = %v, want %v", err, ErrCatchAllPos)
+	}
+	err = pb.SetValid("test", "/dogs/:/edit")
+	if err == nil || !strings.Contains(err.Error(), `":"`) {
+		t.Errorf("Builder.SetValid() error = %v, want it to name the segment %q", err, ":")
+	}
+}
+
+func TestBuilder_SetUnique(t *testing.T) {
+	var pb Builder
+	if err := pb.SetUnique("show_dog", "/dogs/:id"); err != nil {
+		t.Fatalf("Builder.SetUnique() error = %v, want %v", err, nil)
+	}
+	if err := pb.SetUnique("show_dog", "/cats/:id"); err != ErrDuplicate {
+		t.Errorf("Builder.SetUnique() error = %v, want %v", err, ErrDuplicate)
+	}
+	if got, _ := pb.Format("show_dog"); got != "/dogs/:id" {
+		t.Errorf("Builder.Format() = %v, want %v", got, "/dogs/:id")
+	}
+	// Set should still overwrite
+	pb.Set("show_dog", "/cats/:id")
+	if got, _ := pb.Format("show_dog"); got != "/cats/:id" {
+		t.Errorf("Builder.Format() = %v, want %v", got, "/cats/:id")
+	}
+}
+
+func TestBuilder_SetAll(t *testing.T) {
+	var pb Builder
+	pb.SetAll(nil)
+	pb.SetAll(map[string]string{})
+	if got := pb.Names(); len(got) != 0 {
+		t.Errorf("Builder.Names() = %v, want %v", got, []string{})
+	}
+	pb.Set("show_dog", "/cats/:id")
+	pb.SetAll(map[string]string{
+		"show_dog": "/dogs/:id",
+		"edit_dog": "/dogs/:id/edit",
+	})
+	want := map[string]string{
+		"show_dog": "/dogs/:id",
+		"edit_dog": "/dogs/:id/edit",
+	}
+	for name, format := range want {
+		if got, _ := pb.Format(name); got != format {
+			t.Errorf("Builder.Format(%v) = %v, want %v", name, got, format)
+		}
+	}
+}
+
+func TestBuilder_Delete(t *testing.T) {
+	var pb Builder
+	// This should not panic for names that were never set
+	pb.Delete("show_dog")
+	pb.Set("show_dog", "/dogs/:id")
+	pb.Set("edit_dog", "/dogs/:id/edit")
+	pb.Delete("show_dog")
+	if _, err := pb.StrictPath("show_dog", nil); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Builder.StrictPath() error = %v, wantErr %v", err, ErrNotFound)
+	}
+	if got := pb.Path("show_dog", nil); got != "" {
+		t.Errorf("Builder.Path() = %v, want %v", got, "")
+	}
+	if _, err := pb.StrictPath("edit_dog", nil); err != nil {
+		t.Errorf("Builder.Delete(%v) removed %v", "show_dog", "edit_dog")
+	}
+}
+
+func TestBuilder_Reset(t *testing.T) {
+	pb := Builder{IgnoreExtraParams: true}
+	pb.SetDefault("locale", "en")
+	pb.Set("show_dog", "/dogs/:id")
+	pb.Set("edit_dog", "/dogs/:id/edit")
+	pb.Reset()
+	for _, name := range []string{"show_dog", "edit_dog"} {
+		if _, err := pb.StrictPath(name, nil); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Builder.StrictPath(%v) error = %v, want %v", name, err, ErrNotFound)
+		}
+	}
+	if !pb.IgnoreExtraParams {
+		t.Errorf("Builder.Reset() changed IgnoreExtraParams")
+	}
+	pb.IgnoreExtraParams = false
+	pb.Set("home", "/home")
+	if got, want := pb.Path("home", nil), "/home?locale=en"; got != want {
+		t.Errorf("Builder.Path() = %v, want %v", got, want)
+	}
+}
+
+func TestBuilder_Snapshot(t *testing.T) {
+	var pb Builder
+	if got := pb.Snapshot(); len(got) != 0 {
+		t.Errorf("Builder.Snapshot() = %v, want %v", got, map[string]string{})
+	}
+	pb.Set("show_dog", "/dogs/:id")
+	pb.Set("edit_dog", "/dogs/:id/edit")
+	got := pb.Snapshot()
+	want := map[string]string{
+		"show_dog": "/dogs/:id",
+		"edit_dog": "/dogs/:id/edit",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Builder.Snapshot() = %v, want %v", got, want)
+	}
+	// Modifying the snapshot shouldn't affect the Builder
+	got["new_dog"] = "/dogs/new"
+	if pb.Has("new_dog") {
+		t.Errorf("Builder.Has(new_dog) = true after modifying the snapshot")
+	}
+}
+
+func TestBuilder_Replace(t *testing.T) {
+	var pb Builder
+	pb.Set("show_dog", "/dogs/:id")
+	pb.Set("edit_dog", "/dogs/:id/edit")
+	table := pb.Snapshot()
+	table["show_dog"] = "/v2/dogs/:id"
+	delete(table, "edit_dog")
+	table["new_dog"] = "/dogs/new"
+	pb.Replace(table)
+	if got := pb.Snapshot(); !reflect.DeepEqual(got, table) {
+		t.Errorf("Builder.Snapshot() = %v, want %v", got, table)
+	}
+	if got, want := pb.Path("show_dog", map[string]interface{}{"id": 1}), "/v2/dogs/1"; got != want {
+		t.Errorf("Builder.Path() = %v, want %v", got, want)
+	}
+
+	var empty Builder
+	empty.Replace(map[string]string{"show_dog": "/dogs/:id"})
+	empty.Set("edit_dog", "/dogs/:id/edit")
+	if got := empty.Names(); len(got) != 2 {
+		t.Errorf("Builder.Names() = %v, want 2 names", got)
+	}
+}
+
+func TestBuilder_Clone(t *testing.T) {
+	pb := Builder{
+		IgnoreExtraParams:    true,
+		RequireAllParams:     true,
+		MissingParamMode:     EmptySegment,
+		ParamStyle:           BraceStyle,
+		Separator:            "/",
+		BaseURL:              "https://example.com",
+		RawPathParams:        true,
+		Formatter:            func(v interface{}) string { return "formatted" },
+		TimeLayout:           time.Kitchen,
+		NestedQueryParams:    true,
+		OmitEmptyQuery:       true,
+		QueryKeyFunc:         strings.ToUpper,
+		QuerySpaceAsPercent:  true,
+		Encoding:             RFC3986Encoding,
+		BareBoolFlags:        true,
+		TrailingSlash:        TrailingSlashAlways,
+		Lowercase:            true,
+		CollapseSlashes:      true,
+		CaseInsensitiveNames: true,
+		URLTags:              true,
+		Prefix:               "/v1",
+		Locales:              []string{"en"},
+		MatrixParams:         true,
+		Now:                  time.Now,
+		IndexedArrayQuery:    true,
+		DisallowExtraParams:  true,
+		ParamResolver:        func(string) (interface{}, bool) { return nil, false },
+	}
+	pb.Set("show_dog", "/dogs/{id}")
+	pb.SetDefault("locale", "en")
+	c := pb.Clone()
+	if c.IgnoreExtraParams != pb.IgnoreExtraParams ||
+		c.RequireAllParams != pb.RequireAllParams ||
+		c.MissingParamMode != pb.MissingParamMode ||
+		c.ParamStyle != pb.ParamStyle ||
+		c.Separator != pb.Separator ||
+		c.BaseURL != pb.BaseURL ||
+		c.RawPathParams != pb.RawPathParams ||
+		c.Formatter == nil ||
+		c.TimeLayout != pb.TimeLayout ||
+		c.NestedQueryParams != pb.NestedQueryParams ||
+		c.OmitEmptyQuery != pb.OmitEmptyQuery ||
+		c.QueryKeyFunc == nil ||
+		c.QuerySpaceAsPercent != pb.QuerySpaceAsPercent ||
+		c.Encoding != pb.Encoding ||
+		c.BareBoolFlags != pb.BareBoolFlags ||
+		c.TrailingSlash != pb.TrailingSlash ||
+		c.Lowercase != pb.Lowercase ||
+		c.CollapseSlashes != pb.CollapseSlashes ||
+		c.CaseInsensitiveNames != pb.CaseInsensitiveNames ||
+		c.URLTags != pb.URLTags ||
+		c.Prefix != pb.Prefix ||
+		!reflect.DeepEqual(c.Locales, pb.Locales) ||
+		c.MatrixParams != pb.MatrixParams ||
+		c.Now == nil ||
+		c.IndexedArrayQuery != pb.IndexedArrayQuery ||
+		c.DisallowExtraParams != pb.DisallowExtraParams ||
+		c.ParamResolver == nil {
+		t.Errorf("Builder.Clone() options = %+v, want %+v", c, &pb)
+	}
+	got, err := c.StrictPath("show_dog", map[string]interface{}{"id": 123})
+	if err != nil {
+		t.Fatalf("Builder.StrictPath() error = %v, want %v", err, nil)
+	}
+	if want := "https://example.com/v1/dogs/formatted/"; got != want {
+		t.Errorf("Builder.StrictPath() = %v, want %v", got, want)
+	}
+
+	c.Set("edit_dog", "/dogs/{id}/edit")
+	c.Set("show_dog", "/cats/{id}")
+	c.SetDefault("locale", "fr")
+	c.IgnoreExtraParams = false
+	if pb.Has("edit_dog") {
+		t.Errorf("Builder.Set() on the clone changed the original")
+	}
+	if format, _ := pb.Format("show_dog"); format != "/dogs/{id}" {
+		t.Errorf("Builder.Format() = %v, want %v", format, "/dogs/{id}")
+	}
+	if !pb.IgnoreExtraParams {
+		t.Errorf("changing an option on the clone changed the original")
+	}
+	if pb.defaults["locale"] != "en" {
+		t.Errorf("Builder.SetDefault() on the clone changed the original")
+	}
+}
+
+func TestBuilder_Merge(t *testing.T) {
+	var a, b Builder
+	a.Set("show_dog", "/dogs/:id")
+	b.Set("show_dog", "/cats/:id")
+	b.Set("edit_dog", "/dogs/:id/edit")
+	a.Merge(&b)
+	tests := map[string]string{
+		"show_dog": "/dogs/:id",
+		"edit_dog": "/dogs/:id/edit",
+	}
+	for name, want := range tests {
+		if got, _ := a.Format(name); got != want {
+			t.Errorf("Builder.Format(%v) = %v, want %v", name, got, want)
+		}
+	}
+	if got, _ := b.Format("show_dog"); got != "/cats/:id" {
+		t.Errorf("Builder.Merge() changed the other Builder")
+	}
+	// This should not deadlock
+	a.Merge(&a)
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			a.Merge(&b)
+		}()
+		go func() {
+			defer wg.Done()
+			b.Merge(&a)
+		}()
+	}
+	wg.Wait()
+}
+
+func TestBuilder_SetDefault(t *testing.T) {
+	var pb Builder
+	pb.Set("home", "/home")
+	pb.Set("show_page", "/:locale/pages/:id")
+	pb.SetDefault("locale", "en")
+	tests := []struct {
+		name   string
+		path   string
+		params map[string]interface{}
+		ignore bool
+		want   string
+	}{
+		{"default query param", "home", nil, false, "/home?locale=en"},
+		{"default ignored", "home", nil, true, "/home"},
+		{"call site takes precedence", "home", map[string]interface{}{"locale": "fr"}, false, "/home?locale=fr"},
+		{"default fills path", "show_page", map[string]interface{}{"id": 1}, true, "/en/pages/1"},
+		{"call site fills path", "show_page", map[string]interface{}{"id": 1, "locale": "fr"}, false, "/fr/pages/1"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			pb.IgnoreExtraParams = tc.ignore
+			got, err := pb.StrictPath(tc.path, tc.params)
+			if err != nil {
+				t.Fatalf("Builder.StrictPath() error = %v, want %v", err, nil)
+			}
+			if got != tc.want {
+				t.Errorf("Builder.StrictPath() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+
+	pb.IgnoreExtraParams = false
+	pb.DeleteDefault("locale")
+	pb.DeleteDefault("fake_key")
+	if got := pb.Path("home", nil); got != "/home" {
+		t.Errorf("Builder.Path() = %v, want %v", got, "/home")
+	}
+}
+
+func TestBuilder_Has(t *testing.T) {
+	var pb Builder
+	if pb.Has("show_dog") {
+		t.Errorf("Builder.Has(%v) = %v, want %v", "show_dog", true, false)
+	}
+	pb.Set("show_dog", "/dogs/:id")
+	if !pb.Has("show_dog") {
+		t.Errorf("Builder.Has(%v) = %v, want %v", "show_dog", false, true)
+	}
+	if pb.Has("edit_dog") {
+		t.Errorf("Builder.Has(%v) = %v, want %v", "edit_dog", true, false)
+	}
+}
+
+func TestBuilder_Format(t *testing.T) {
+	var pb Builder
+	pb.Set("show_dog", "/dogs/:id")
+	tests := []struct {
+		name, path, want string
+		wantOk           bool
+	}{
+		{"missing path", "fake_path", "", false},
+		{"existing path is not expanded", "show_dog", "/dogs/:id", true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, ok := pb.Format(tc.path)
+			if ok != tc.wantOk {
+				t.Errorf("Builder.Format(%v) ok = %v, want %v", tc.path, ok, tc.wantOk)
+			}
+			if got != tc.want {
+				t.Errorf("Builder.Format(%v) = %v, want %v", tc.path, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestBuilder_Params(t *testing.T) {
+	var pb Builder
+	pb.Set("create_dog", "/dogs/")
+	pb.Set("show_toy", "/dogs/:id/toys/:toy_id")
+	pb.Set("repeated", "/:b/:a/:b/*rest")
+	pb.Set("optional", "/posts/:id/:slug?")
+	tests := []struct {
+		name    string
+		path    string
+		want    []string
+		wantErr error
+	}{
+		{"no params", "create_dog", []string{}, nil},
+		{"in order", "show_toy", []string{"id", "toy_id"}, nil},
+		{"deduplicated", "repeated", []string{"b", "a", "rest"}, nil},
+		{"optional", "optional", []string{"id", "slug"}, nil},
+		{"missing path", "fake_path", nil, ErrNotFound},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := pb.Params(tc.path)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("Builder.Params() error = %v, want %v", err, tc.wantErr)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("Builder.Params() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestBuilder_Names(t *testing.T) {
+	var pb Builder
+	if got := pb.Names(); len(got) != 0 {
+		t.Errorf("Builder.Names() = %v, want %v", got, []string{})
+	}
+	pb.Set("show_dog", "/dogs/:id")
+	pb.Set("create_dog", "/dogs/")
+	pb.Set("edit_dog", "/dogs/:id/edit")
+	want := []string{"create_dog", "edit_dog", "show_dog"}
+	got := pb.Names()
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Builder.Names() = %v, want %v", got, want)
+	}
+	// Mutating the result shouldn't change the builder
+	got[0] = "fake_path"
+	if !pb.Has("create_dog") || pb.Has("fake_path") {
+		t.Errorf("Builder.Names() returned a slice tied to internal state")
+	}
+}
+
+func TestBuilder_RoutesUsing(t *testing.T) {
+	var pb Builder
+	pb.Set("show_dog", "/:tenant/dogs/:id")
+	pb.Set("edit_dog", "/:tenant/dogs/:id/edit")
+	pb.Set("show_toy", "/toys/:toy_id")
+	pb.Set("tenants", "/tenants/*tenant_path")
+	pb.Set("report", "/reports/:tenant.csv")
+	tests := []struct {
+		param string
+		want  []string
+	}{
+		{"tenant", []string{"edit_dog", "report", "show_dog"}},
+		{"id", []string{"edit_dog", "show_dog"}},
+		{"tenant_path", []string{"tenants"}},
+		{"toy", nil},
+		{"missing", nil},
+	}
+	for _, tc := range tests {
+		t.Run(tc.param, func(t *testing.T) {
+			if got := pb.RoutesUsing(tc.param); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("Builder.RoutesUsing() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestBuilder_Walk(t *testing.T) {
+	var pb Builder
+	pb.Set("show_dog", "/dogs/:id")
+	pb.Set("create_dog", "/dogs/")
+	pb.Set("edit_dog", "/dogs/:id/edit")
+	var got []string
+	err := pb.Walk(func(name, format string) error {
+		got = append(got, name+" "+format)
+		// This should not deadlock
+		pb.Set("new_"+name, format)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Builder.Walk() error = %v, want %v", err, nil)
+	}
+	want := []string{
+		"create_dog /dogs/",
+		"edit_dog /dogs/:id/edit",
+		"show_dog /dogs/:id",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Builder.Walk() visited %v, want %v", got, want)
+	}
+
+	wantErr := fmt.Errorf("stop")
+	calls := 0
+	err = pb.Walk(func(name, format string) error {
+		calls++
+		return wantErr
+	})
+	if err != wantErr {
+		t.Errorf("Builder.Walk() error = %v, want %v", err, wantErr)
+	}
+	if calls != 1 {
+		t.Errorf("Builder.Walk() calls = %v, want %v", calls, 1)
+	}
+}
+
+func TestBuilder_String(t *testing.T) {
+	var pb Builder
+	if got := pb.String(); got != "" {
+		t.Errorf("Builder.String() = %q, want %q", got, "")
+	}
+	pb.Set("show_dog", "/dogs/:id")
+	pb.Set("edit_dog", "/dogs/:id/edit")
+	want := "edit_dog => /dogs/:id/edit\nshow_dog => /dogs/:id\n"
+	if got := pb.String(); got != want {
+		t.Errorf("Builder.String() = %q, want %q", got, want)
+	}
+	if got := fmt.Sprint(&pb); got != want {
+		t.Errorf("fmt.Sprint(Builder) = %q, want %q", got, want)
+	}
+}
+
 func TestBuilder_Path(t *testing.T) {
 	var pb Builder
 	pb.Set("show_dog", "/dogs/:id")
@@ -48,6 +677,54 @@ func TestBuilder_Path(t *testing.T) {
 	}
 }
 
+func TestBuilder_Query(t *testing.T) {
+	pb := Builder{
+		IgnoreExtraParams: true,
+		NestedQueryParams: true,
+	}
+	tests := []struct {
+		name   string
+		params map[string]interface{}
+		want   string
+	}{
+		{"nil", nil, ""},
+		{"empty", map[string]interface{}{}, ""},
+		{"params", map[string]interface{}{"name": "felix", "age": 12}, "age=12&name=felix"},
+		{"escaped", map[string]interface{}{"name": "jane doe"}, "name=jane+doe"},
+		{"slices", map[string]interface{}{"tag": []string{"a", "b"}}, "tag=a&tag=b"},
+		{"nested", map[string]interface{}{"f": map[string]string{"x": "1"}}, "f%5Bx%5D=1"},
+		{"in query", map[string]interface{}{"id": InQuery(1)}, "id=1"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := pb.Query(tc.params); got != tc.want {
+				t.Errorf("Builder.Query() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestBuilder_PathOrDefault(t *testing.T) {
+	pb := Builder{RequireAllParams: true}
+	pb.Set("show_dog", "/dogs/:id")
+	tests := []struct {
+		name, path, want string
+		params           map[string]interface{}
+	}{
+		{"missing path returns fallback", "fake_path", "#", nil},
+		{"errors return fallback", "show_dog", "#", nil},
+		{"existing paths work", "show_dog", "/dogs/123", map[string]interface{}{"id": 123}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := pb.PathOrDefault(tc.path, tc.params, "#")
+			if got != tc.want {
+				t.Errorf("Builder.PathOrDefault(%v) = %v, want %v", tc.path, got, tc.want)
+			}
+		})
+	}
+}
+
 // Like 90% of this functionality is tested in Test_replace, so all
 // I really want to test is that we pass the correct argus into replace.
 func TestBuilder_StrictPath(t *testing.T) {
@@ -117,7 +794,7 @@ func TestBuilder_StrictPath(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			pb.IgnoreExtraParams = tc.ignoreParams
 			got, err := pb.StrictPath(tc.args.name, tc.args.params)
-			if err != tc.wantErr {
+			if !errors.Is(err, tc.wantErr) {
 				t.Fatalf("Builder.StrictPath() error = %v, wantErr %v", err, tc.wantErr)
 			}
 			if got != tc.want {
@@ -127,135 +804,1749 @@ func TestBuilder_StrictPath(t *testing.T) {
 	}
 }
 
-func TestBuilder_init(t *testing.T) {
-	var b Builder
-	b.init()
-	// This should not panic after we init
-	b.paths["key"] = "value"
-}
-
-func Test_replace(t *testing.T) {
-	type args struct {
-		path   string
-		params map[string]interface{}
+func TestBuilder_PathE(t *testing.T) {
+	pb := Builder{RequireAllParams: true}
+	pb.Set("show_dog", "/dogs/:id")
+	tests := []struct {
+		name   string
+		path   string
+		params map[string]interface{}
+	}{
+		{"found", "show_dog", map[string]interface{}{"id": 123, "page": 2}},
+		{"missing param", "show_dog", nil},
+		{"not found", "fake_path", nil},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := pb.PathE(tc.path, tc.params)
+			want, wantErr := pb.StrictPath(tc.path, tc.params)
+			if got != want || !reflect.DeepEqual(err, wantErr) {
+				t.Errorf("Builder.PathE() = %v, %v, want %v, %v", got, err, want, wantErr)
+			}
+		})
+	}
+}
+
+func TestBuilder_StrictPath_notFound(t *testing.T) {
+	var pb Builder
+	_, err := pb.StrictPath("fake_path", nil)
+	nfe, ok := err.(*NotFoundError)
+	if !ok {
+		t.Fatalf("Builder.StrictPath() error = %v, want %T", err, nfe)
+	}
+	if nfe.Name != "fake_path" {
+		t.Errorf("NotFoundError.Name = %v, want %v", nfe.Name, "fake_path")
+	}
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("errors.Is(%v, ErrNotFound) = false, want true", err)
+	}
+	if !strings.Contains(err.Error(), "fake_path") {
+		t.Errorf("NotFoundError.Error() = %v, want it to include %v", err.Error(), "fake_path")
+	}
+}
+
+func TestBuilder_StrictPath_requireAllParams(t *testing.T) {
+	pb := Builder{RequireAllParams: true}
+	pb.Set("edit_dog", "/dogs/:id/edit")
+	_, err := pb.StrictPath("edit_dog", nil)
+	mpe, ok := err.(*MissingParamError)
+	if !ok {
+		t.Fatalf("Builder.StrictPath() error = %v, want %T", err, mpe)
+	}
+	if mpe.Key != "id" {
+		t.Errorf("MissingParamError.Key = %v, want %v", mpe.Key, "id")
+	}
+	if !mpe.Is(ErrMissingParam) {
+		t.Errorf("MissingParamError.Is(ErrMissingParam) = false, want true")
+	}
+	got, err := pb.StrictPath("edit_dog", map[string]interface{}{"id": 123})
+	if err != nil {
+		t.Fatalf("Builder.StrictPath() error = %v, want %v", err, nil)
+	}
+	if want := "/dogs/123/edit"; got != want {
+		t.Errorf("Builder.StrictPath() = %v, want %v", got, want)
+	}
+	if got := pb.Path("edit_dog", nil); got != "" {
+		t.Errorf("Builder.Path() = %v, want %v", got, "")
+	}
+
+	// A port or a time isn't a param, so it's never missing.
+	pb.Set("api", "http://localhost:8080/dogs/:id")
+	pb.Set("at", "/at/10:30")
+	got, err = pb.StrictPath("api", map[string]interface{}{"id": 123})
+	if err != nil {
+		t.Fatalf("Builder.StrictPath() error = %v, want %v", err, nil)
+	}
+	if want := "http://localhost:8080/dogs/123"; got != want {
+		t.Errorf("Builder.StrictPath() = %v, want %v", got, want)
+	}
+	got, err = pb.StrictPath("at", nil)
+	if err != nil {
+		t.Fatalf("Builder.StrictPath() error = %v, want %v", err, nil)
+	}
+	if want := "/at/10:30"; got != want {
+		t.Errorf("Builder.StrictPath() = %v, want %v", got, want)
+	}
+}
+
+func TestBuilder_PathOrdered(t *testing.T) {
+	var pb Builder
+	pb.Set("show_dog", "/dogs/:id")
+	params := map[string]interface{}{
+		"id":    123,
+		"color": "brown",
+		"age":   12,
+		"name":  "felix",
+		"tag":   []string{"a", "b"},
+	}
+	tests := []struct {
+		name  string
+		order []string
+		want  string
+	}{
+		{"no order", nil, "/dogs/123?age=12&color=brown&name=felix&tag=a&tag=b"},
+		{"partial order", []string{"name", "tag"}, "/dogs/123?name=felix&tag=a&tag=b&age=12&color=brown"},
+		{"full order", []string{"tag", "name", "color", "age"}, "/dogs/123?tag=a&tag=b&name=felix&color=brown&age=12"},
+		{"unknown and path keys", []string{"id", "fake", "name", "name"}, "/dogs/123?name=felix&age=12&color=brown&tag=a&tag=b"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := pb.PathOrdered("show_dog", params, tc.order)
+			if err != nil {
+				t.Fatalf("Builder.PathOrdered() error = %v, want %v", err, nil)
+			}
+			if got != tc.want {
+				t.Errorf("Builder.PathOrdered() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestBuilder_LocalePath(t *testing.T) {
+	tests := []struct {
+		name    string
+		locale  string
+		prefix  string
+		baseURL string
+		params  map[string]interface{}
+		want    string
+		wantErr error
+	}{
+		{"locale", "fr", "", "", map[string]interface{}{"id": 1}, "/fr/dogs/1", nil},
+		{"query", "en", "", "", map[string]interface{}{"id": 1, "page": 2}, "/en/dogs/1?page=2", nil},
+		{"prefix", "en", "/v1/", "", map[string]interface{}{"id": 1}, "/v1/en/dogs/1", nil},
+		{"base url", "fr", "", "https://example.com/", map[string]interface{}{"id": 1}, "https://example.com/fr/dogs/1", nil},
+		{"unknown locale", "de", "", "", map[string]interface{}{"id": 1}, "", ErrLocale},
+		{"empty locale", "", "", "", map[string]interface{}{"id": 1}, "", ErrLocale},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			pb := Builder{Locales: []string{"en", "fr"}, Prefix: tc.prefix, BaseURL: tc.baseURL}
+			pb.Set("show_dog", "/dogs/:id")
+			got, err := pb.LocalePath(tc.locale, "show_dog", tc.params)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("Builder.LocalePath() error = %v, want %v", err, tc.wantErr)
+			}
+			if got != tc.want {
+				t.Errorf("Builder.LocalePath() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+
+	var pb Builder
+	pb.Set("show_dog", "/dogs/:id")
+	if _, err := pb.LocalePath("en", "show_dog", nil); !errors.Is(err, ErrLocale) {
+		t.Errorf("Builder.LocalePath() error = %v, want %v", err, ErrLocale)
+	}
+}
+
+func TestBuilder_PathWithValues(t *testing.T) {
+	tests := []struct {
+		name   string
+		ignore bool
+		params map[string]interface{}
+		q      url.Values
+		want   string
+	}{
+		{"values", false, map[string]interface{}{"id": 1}, url.Values{"tag": {"a b", "c&d"}}, "/dogs/1?tag=a+b&tag=c%26d"},
+		{"no values", false, map[string]interface{}{"id": 1}, nil, "/dogs/1"},
+		{"merged", false, map[string]interface{}{"id": 1, "page": 2, "tag": "x"}, url.Values{"tag": {"a"}, "b": {"1"}}, "/dogs/1?b=1&page=2&tag=x&tag=a"},
+		{"path param in values", false, map[string]interface{}{"id": 1}, url.Values{"id": {"2"}}, "/dogs/1?id=2"},
+		{"ignore extra params", true, map[string]interface{}{"id": 1, "page": 2}, url.Values{"tag": {"a"}}, "/dogs/1?tag=a"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			pb := Builder{IgnoreExtraParams: tc.ignore}
+			pb.Set("show_dog", "/dogs/:id")
+			got, err := pb.PathWithValues("show_dog", tc.params, tc.q)
+			if err != nil {
+				t.Fatalf("Builder.PathWithValues() error = %v, want %v", err, nil)
+			}
+			if got != tc.want {
+				t.Errorf("Builder.PathWithValues() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestBuilder_PathFiltered(t *testing.T) {
+	nonZero := func(key string, value interface{}) bool {
+		return !reflect.ValueOf(value).IsZero()
+	}
+	tests := []struct {
+		name    string
+		params  map[string]interface{}
+		missing MissingParamMode
+		want    string
+		wantErr bool
+	}{
+		{"query", map[string]interface{}{"id": 1, "q": "", "page": 0, "sort": "name"}, KeepPlaceholder, "/dogs/1?sort=name", false},
+		{"in query", map[string]interface{}{"id": 1, "page": InQuery(0), "sort": InQuery("name")}, KeepPlaceholder, "/dogs/1?sort=name", false},
+		{"path keep placeholder", map[string]interface{}{"id": 0}, KeepPlaceholder, "/dogs/:id", false},
+		{"path error", map[string]interface{}{"id": 0}, ReturnError, "", true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			pb := Builder{MissingParamMode: tc.missing}
+			pb.Set("show_dog", "/dogs/:id")
+			got, err := pb.PathFiltered("show_dog", tc.params, nonZero)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("Builder.PathFiltered() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if got != tc.want {
+				t.Errorf("Builder.PathFiltered() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestBuilder_PathWithFragment(t *testing.T) {
+	var pb Builder
+	pb.Set("show_doc", "/docs/:page")
+	tests := []struct {
+		name     string
+		path     string
+		params   map[string]interface{}
+		fragment string
+		want     string
+		wantErr  error
+	}{
+		{"no fragment", "show_doc", map[string]interface{}{"page": "intro"}, "", "/docs/intro", nil},
+		{"fragment", "show_doc", map[string]interface{}{"page": "intro"}, "installation", "/docs/intro#installation", nil},
+		{"fragment after query", "show_doc", map[string]interface{}{"page": "intro", "x": 1}, "installation", "/docs/intro?x=1#installation", nil},
+		{"fragment is escaped", "show_doc", map[string]interface{}{"page": "intro"}, "getting started", "/docs/intro#getting%20started", nil},
+		{"missing path", "fake_path", nil, "installation", "", ErrNotFound},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := pb.PathWithFragment(tc.path, tc.params, tc.fragment)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("Builder.PathWithFragment() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if got != tc.want {
+				t.Errorf("Builder.PathWithFragment() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestBuilder_URL(t *testing.T) {
+	var pb Builder
+	pb.Set("show_dog", "/dogs/:id")
+	got, err := pb.URL("show_dog", map[string]interface{}{"id": "a/b c", "q": "x y"})
+	if err != nil {
+		t.Fatalf("Builder.URL() error = %v, want %v", err, nil)
+	}
+	if want := "/dogs/a/b c"; got.Path != want {
+		t.Errorf("Builder.URL().Path = %v, want %v", got.Path, want)
+	}
+	if want := "q=x+y"; got.RawQuery != want {
+		t.Errorf("Builder.URL().RawQuery = %v, want %v", got.RawQuery, want)
+	}
+	if want := "/dogs/a%2Fb%20c?q=x+y"; got.String() != want {
+		t.Errorf("Builder.URL().String() = %v, want %v", got.String(), want)
+	}
+
+	pb.BaseURL = "https://example.com"
+	got, err = pb.URL("show_dog", map[string]interface{}{"id": 123})
+	if err != nil {
+		t.Fatalf("Builder.URL() error = %v, want %v", err, nil)
+	}
+	if got.Scheme != "https" || got.Host != "example.com" || got.Path != "/dogs/123" {
+		t.Errorf("Builder.URL() = %v, want %v", got, "https://example.com/dogs/123")
+	}
+
+	if _, err := pb.URL("fake_path", nil); err == nil {
+		t.Errorf("Builder.URL() error = %v, want an error", err)
+	}
+}
+
+func TestBuilder_AbsoluteURL(t *testing.T) {
+	pb := Builder{BaseURL: "https://ignored.com"}
+	pb.Set("show_dog", "/dogs/:id")
+	pb.Set("relative", "dogs/:id")
+	tests := []struct {
+		name    string
+		scheme  string
+		host    string
+		path    string
+		params  map[string]interface{}
+		want    string
+		wantErr bool
+	}{
+		{"basic", "https", "example.com", "show_dog", map[string]interface{}{"id": 123}, "https://example.com/dogs/123", false},
+		{"port", "http", "localhost:3000", "show_dog", map[string]interface{}{"id": 123}, "http://localhost:3000/dogs/123", false},
+		{"escaping and query", "https", "example.com", "show_dog", map[string]interface{}{"id": "a b", "q": "x&y"}, "https://example.com/dogs/a%20b?q=x%26y", false},
+		{"relative format", "https", "example.com", "relative", map[string]interface{}{"id": 123}, "https://example.com/dogs/123", false},
+		{"missing scheme", "", "example.com", "show_dog", nil, "", true},
+		{"missing path", "https", "example.com", "fake_path", nil, "", true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := pb.AbsoluteURL(tc.scheme, tc.host, tc.path, tc.params)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("Builder.AbsoluteURL() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if got != tc.want {
+				t.Errorf("Builder.AbsoluteURL() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestBuilder_PathBytes(t *testing.T) {
+	tests := []struct {
+		name    string
+		base    string
+		format  string
+		params  map[string]interface{}
+		wantErr bool
+	}{
+		{"path", "", "/dogs/:id", map[string]interface{}{"id": "a b"}, false},
+		{"query", "", "/dogs/:id", map[string]interface{}{"id": 1, "q": "x y"}, false},
+		{"base url", "https://example.com/", "/dogs/:id", map[string]interface{}{"id": 1}, false},
+		{"base url and relative format", "https://example.com", "dogs/:id", map[string]interface{}{"id": 1}, false},
+		{"base url and empty format", "https://example.com/", "", nil, false},
+		{"invalid base url", "example.com", "/dogs", nil, true},
+		{"invalid format", "", "/files/*rest/edit", nil, true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			pb := Builder{BaseURL: tc.base}
+			pb.Set("test", tc.format)
+			got, err := pb.PathBytes("test", tc.params)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("Builder.PathBytes() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			want, _ := pb.StrictPath("test", tc.params)
+			if string(got) != want {
+				t.Errorf("Builder.PathBytes() = %s, want %v", got, want)
+			}
+		})
+	}
+	if _, err := (&Builder{}).PathBytes("fake_path", nil); err == nil {
+		t.Errorf("Builder.PathBytes() error = %v, want an error", err)
+	}
+}
+
+func TestBuilder_WritePath(t *testing.T) {
+	pb := Builder{BaseURL: "https://example.com"}
+	pb.Set("show_dog", "/dogs/:id")
+	var sb strings.Builder
+	sb.WriteString("<a href=\"")
+	params := map[string]interface{}{"id": "a b", "q": 1}
+	if err := pb.WritePath(&sb, "show_dog", params); err != nil {
+		t.Fatalf("Builder.WritePath() error = %v, want %v", err, nil)
+	}
+	sb.WriteString("\">")
+	want, _ := pb.StrictPath("show_dog", params)
+	if got := sb.String(); got != "<a href=\""+want+"\">" {
+		t.Errorf("Builder.WritePath() wrote %v, want %v", got, "<a href=\""+want+"\">")
+	}
+
+	sb.Reset()
+	err := pb.WritePath(&sb, "fake_path", nil)
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("Builder.WritePath() error = %v, want %v", err, ErrNotFound)
+	}
+	if sb.Len() != 0 {
+		t.Errorf("Builder.WritePath() wrote %q, want nothing", sb.String())
+	}
+}
+
+func TestBuilder_MustPath(t *testing.T) {
+	var pb Builder
+	pb.Set("show_dog", "/dogs/:id")
+	got := pb.MustPath("show_dog", map[string]interface{}{"id": 123})
+	if want := "/dogs/123"; got != want {
+		t.Errorf("Builder.MustPath() = %v, want %v", got, want)
+	}
+
+	defer func() {
+		r := recover()
+		if r == nil {
+			t.Fatalf("Builder.MustPath() didn't panic")
+		}
+		if msg := fmt.Sprintf("%v", r); !strings.Contains(msg, "fake_path") {
+			t.Errorf("Builder.MustPath() panic = %v, want it to include %v", msg, "fake_path")
+		}
+	}()
+	pb.MustPath("fake_path", nil)
+}
+
+// This test is only really useful when run with the race
+// detector, eg `go test -race`.
+func TestBuilder_concurrency(t *testing.T) {
+	var pb Builder
+	pb.Set("show_dog", "/dogs/:id")
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			pb.Set(fmt.Sprintf("dog_%d", i), "/dogs/:id")
+		}(i)
+		go func() {
+			defer wg.Done()
+			pb.StrictPath("show_dog", map[string]interface{}{"id": 123})
+		}()
+	}
+	wg.Wait()
+
+	// Read-only methods on a zero Builder must not initialize
+	// it while other readers hold the read lock.
+	var zero Builder
+	for i := 0; i < 10; i++ {
+		wg.Add(3)
+		go func() {
+			defer wg.Done()
+			zero.Has("a")
+		}()
+		go func() {
+			defer wg.Done()
+			zero.Names()
+		}()
+		go func() {
+			defer wg.Done()
+			zero.StrictPath("a", nil)
+		}()
+	}
+	wg.Wait()
+}
+
+func TestBuilder_StrictPath_paramStyle(t *testing.T) {
+	pb := Builder{ParamStyle: BraceStyle}
+	pb.Set("edit_dog", "/dogs/{id}/edit")
+	pb.Set("colon_dog", "/dogs/:id")
+	got, err := pb.StrictPath("edit_dog", map[string]interface{}{"id": 123})
+	if err != nil {
+		t.Fatalf("Builder.StrictPath() error = %v, want %v", err, nil)
+	}
+	if want := "/dogs/123/edit"; got != want {
+		t.Errorf("Builder.StrictPath() = %v, want %v", got, want)
+	}
+	got, err = pb.StrictPath("colon_dog", map[string]interface{}{"id": 123})
+	if err != nil {
+		t.Fatalf("Builder.StrictPath() error = %v, want %v", err, nil)
+	}
+	if want := "/dogs/:id?id=123"; got != want {
+		t.Errorf("Builder.StrictPath() = %v, want %v", got, want)
+	}
+}
+
+func TestBuilder_StrictPath_baseURL(t *testing.T) {
+	tests := []struct {
+		name    string
+		baseURL string
+		path    string
+		params  map[string]interface{}
+		want    string
+		wantErr bool
+	}{
+		{"no base url", "", "show_dog", map[string]interface{}{"id": 123}, "/dogs/123", false},
+		{"base url", "https://example.com", "show_dog", map[string]interface{}{"id": 123}, "https://example.com/dogs/123", false},
+		{"trailing slash", "https://example.com/", "show_dog", map[string]interface{}{"id": 123}, "https://example.com/dogs/123", false},
+		{"base url with path", "https://example.com/app/", "show_dog", map[string]interface{}{"id": 123}, "https://example.com/app/dogs/123", false},
+		{"query is kept", "https://example.com", "create_dog", map[string]interface{}{"age": 12}, "https://example.com/dogs/?age=12", false},
+		{"missing host", "/just/a/path", "show_dog", nil, "", true},
+		{"invalid url", "https://example.com/%zz", "show_dog", nil, "", true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			pb := Builder{BaseURL: tc.baseURL}
+			pb.Set("create_dog", "/dogs/")
+			pb.Set("show_dog", "/dogs/:id")
+			got, err := pb.StrictPath(tc.path, tc.params)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("Builder.StrictPath() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if got != tc.want {
+				t.Errorf("Builder.StrictPath() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+
+	// The BaseURL is checked again whenever it changes
+	pb := Builder{BaseURL: "https://example.com"}
+	pb.Set("show_dog", "/dogs/:id")
+	for _, tc := range []struct {
+		baseURL string
+		wantErr bool
+	}{
+		{"https://example.com", false},
+		{"/just/a/path", true},
+		{"https://example.com", false},
+		{"https://example.com/%zz", true},
+	} {
+		pb.BaseURL = tc.baseURL
+		if _, err := pb.StrictPath("show_dog", map[string]interface{}{"id": 1}); (err != nil) != tc.wantErr {
+			t.Errorf("Builder.StrictPath() with BaseURL %v error = %v, wantErr %v", tc.baseURL, err, tc.wantErr)
+		}
+	}
+}
+
+func TestBuilder_StrictPath_prefix(t *testing.T) {
+	tests := []struct {
+		name    string
+		baseURL string
+		prefix  string
+		path    string
+		params  map[string]interface{}
+		want    string
+	}{
+		{"no prefix", "", "", "show_dog", map[string]interface{}{"id": 123}, "/dogs/123"},
+		{"prefix", "", "/v1", "show_dog", map[string]interface{}{"id": 123}, "/v1/dogs/123"},
+		{"prefix without leading slash", "", "v1", "show_dog", map[string]interface{}{"id": 123}, "/v1/dogs/123"},
+		{"prefix with trailing slash", "", "/v1/", "show_dog", map[string]interface{}{"id": 123}, "/v1/dogs/123"},
+		{"prefix and base url", "https://example.com/", "/v1/", "show_dog", map[string]interface{}{"id": 123}, "https://example.com/v1/dogs/123"},
+		{"prefix and query", "", "/v1", "create_dog", map[string]interface{}{"age": 12}, "/v1/dogs/?age=12"},
+		{"prefix and empty format", "", "/v1", "root", nil, "/v1"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			pb := Builder{BaseURL: tc.baseURL, Prefix: tc.prefix}
+			pb.Set("create_dog", "/dogs/")
+			pb.Set("show_dog", "/dogs/:id")
+			pb.Set("root", "")
+			got, err := pb.StrictPath(tc.path, tc.params)
+			if err != nil {
+				t.Fatalf("Builder.StrictPath() error = %v, want %v", err, nil)
+			}
+			if got != tc.want {
+				t.Errorf("Builder.StrictPath() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+
+	// Changing the prefix affects paths that are already set
+	var pb Builder
+	pb.Set("show_dog", "/dogs/:id")
+	pb.Prefix = "/v2"
+	if got, want := pb.Path("show_dog", map[string]interface{}{"id": 1}), "/v2/dogs/1"; got != want {
+		t.Errorf("Builder.Path() = %v, want %v", got, want)
+	}
+	if err := pb.Verify("show_dog", map[string]interface{}{"id": 1}); err != nil {
+		t.Errorf("Builder.Verify() error = %v, want %v", err, nil)
+	}
+}
+
+func TestBuilder_StrictPath_disallowExtraParams(t *testing.T) {
+	tests := []struct {
+		name     string
+		disallow bool
+		ignore   bool
+		params   map[string]interface{}
+		want     string
+		wantKeys []string
+	}{
+		{"no extra params", true, false, map[string]interface{}{"id": 1}, "/dogs/1?locale=en&unused=x", nil},
+		{"extra params", true, false, map[string]interface{}{"id": 1, "nmae": "felix", "age": 2}, "", []string{"age", "nmae"}},
+		{"in query", true, false, map[string]interface{}{"id": 1, "page": InQuery(2)}, "/dogs/1?locale=en&page=2&unused=x", nil},
+		{"takes precedence over ignore", true, true, map[string]interface{}{"id": 1, "age": 2}, "", []string{"age"}},
+		{"without the flag", false, false, map[string]interface{}{"id": 1, "age": 2}, "/dogs/1?age=2&locale=en&unused=x", nil},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			pb := Builder{DisallowExtraParams: tc.disallow, IgnoreExtraParams: tc.ignore}
+			pb.Set("show_dog", "/dogs/:id")
+			pb.SetDefault("locale", InQuery("en"))
+			pb.SetDefault("unused", "x")
+			got, err := pb.StrictPath("show_dog", tc.params)
+			if tc.wantKeys == nil && err != nil {
+				t.Fatalf("Builder.StrictPath() error = %v, want %v", err, nil)
+			}
+			if tc.wantKeys != nil {
+				epe, ok := err.(*ExtraParamsError)
+				if !ok || !reflect.DeepEqual(epe.Keys, tc.wantKeys) {
+					t.Fatalf("Builder.StrictPath() error = %v, want extra %v", err, tc.wantKeys)
+				}
+				if !errors.Is(err, ErrExtraParams) {
+					t.Errorf("errors.Is(%v, ErrExtraParams) = false, want true", err)
+				}
+			}
+			if got != tc.want {
+				t.Errorf("Builder.StrictPath() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestBuilder_StrictPath_missingParamMode(t *testing.T) {
+	tests := []struct {
+		name       string
+		mode       MissingParamMode
+		requireAll bool
+		want       string
+		wantErr    bool
+	}{
+		{"keep placeholder", KeepPlaceholder, false, "/dogs/123/toys/:toy_id", false},
+		{"empty segment", EmptySegment, false, "/dogs/123/toys/", false},
+		{"return error", ReturnError, false, "", true},
+		{"require all params takes precedence", EmptySegment, true, "", true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			pb := Builder{
+				MissingParamMode: tc.mode,
+				RequireAllParams: tc.requireAll,
+			}
+			pb.Set("show_toy", "/dogs/:id/toys/:toy_id")
+			got, err := pb.StrictPath("show_toy", map[string]interface{}{"id": 123})
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("Builder.StrictPath() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if got != tc.want {
+				t.Errorf("Builder.StrictPath() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestBuilder_StrictPath_paramResolver(t *testing.T) {
+	resolver := func(key string) (interface{}, bool) {
+		switch key {
+		case "tenant":
+			return "acme", true
+		case "locale":
+			return "fr", true
+		}
+		return nil, false
+	}
+	tests := []struct {
+		name     string
+		format   string
+		params   map[string]interface{}
+		resolver func(string) (interface{}, bool)
+		want     string
+	}{
+		{"resolved", "/:tenant/dogs/:id", map[string]interface{}{"id": 1}, resolver, "/acme/dogs/1?locale=en"},
+		{"provided", "/:tenant/dogs/:id", map[string]interface{}{"id": 1, "tenant": "other"}, resolver, "/other/dogs/1?locale=en"},
+		{"not resolved", "/:tenant/dogs/:id", map[string]interface{}{}, resolver, "/acme/dogs/:id?locale=en"},
+		{"over defaults", "/:locale/dogs", nil, resolver, "/fr/dogs"},
+		{"over inline defaults", "/:tenant=none/dogs", nil, resolver, "/acme/dogs?locale=en"},
+		{"query params", "/dogs", nil, resolver, "/dogs?locale=en"},
+		{"no resolver", "/:tenant/dogs", nil, nil, "/:tenant/dogs?locale=en"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			pb := Builder{ParamResolver: tc.resolver}
+			pb.Set("test", tc.format)
+			pb.SetDefault("locale", "en")
+			got, err := pb.StrictPath("test", tc.params)
+			if err != nil {
+				t.Fatalf("Builder.StrictPath() error = %v, want %v", err, nil)
+			}
+			if got != tc.want {
+				t.Errorf("Builder.StrictPath() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestBuilder_separator(t *testing.T) {
+	pb := Builder{Separator: ".", IgnoreExtraParams: true}
+	pb.Set("dog", "cache.dogs.:id.name")
+	pb.Set("files", "files.*rest")
+	params := map[string]interface{}{"id": 123, "page": 2}
+	if got, want := pb.Path("dog", params), "cache.dogs.123.name"; got != want {
+		t.Errorf("Builder.Path() = %v, want %v", got, want)
+	}
+	if got, ok := pb.Match("dog", "cache.dogs.123.name"); !ok || got["id"] != "123" {
+		t.Errorf("Builder.Match() = %v, %v, want %v", got, ok, map[string]string{"id": "123"})
+	}
+	if _, ok := pb.Match("dog", "cache/dogs/123/name"); ok {
+		t.Errorf("Builder.Match() ok = %v, want %v", ok, false)
+	}
+
+	params = map[string]interface{}{"rest": "a.b"}
+	if got, want := pb.Path("files", params), "files.a.b"; got != want {
+		t.Errorf("Builder.Path() = %v, want %v", got, want)
+	}
+
+	// Changing the separator affects paths that are already set
+	pb.Separator = ""
+	if got, want := pb.Path("files", params), "files.*rest"; got != want {
+		t.Errorf("Builder.Path() = %v, want %v", got, want)
+	}
+}
+
+func TestBuilder_StrictPath_collapseSlashes(t *testing.T) {
+	pb := Builder{BaseURL: "https://example.com/", CollapseSlashes: true}
+	pb.Set("edit_dog", "//dogs//:id//edit")
+	got, err := pb.StrictPath("edit_dog", map[string]interface{}{"id": 1, "next": "https://example.com"})
+	if err != nil {
+		t.Fatalf("Builder.StrictPath() error = %v, want %v", err, nil)
+	}
+	if want := "https://example.com/dogs/1/edit?next=https%3A%2F%2Fexample.com"; got != want {
+		t.Errorf("Builder.StrictPath() = %v, want %v", got, want)
+	}
+}
+
+func TestBuilder_caseInsensitiveNames(t *testing.T) {
+	pb := Builder{CaseInsensitiveNames: true}
+	pb.Set("Show_Dog", "/Dogs/:ID")
+	tests := []string{"Show_Dog", "show_dog", "SHOW_DOG"}
+	for _, name := range tests {
+		got, err := pb.StrictPath(name, map[string]interface{}{"ID": 123})
+		if err != nil {
+			t.Fatalf("Builder.StrictPath(%v) error = %v, want %v", name, err, nil)
+		}
+		if want := "/Dogs/123"; got != want {
+			t.Errorf("Builder.StrictPath(%v) = %v, want %v", name, got, want)
+		}
+		if !pb.Has(name) {
+			t.Errorf("Builder.Has(%v) = false, want true", name)
+		}
+	}
+	if err := pb.SetUnique("SHOW_dog", "/cats"); err != ErrDuplicate {
+		t.Errorf("Builder.SetUnique() error = %v, want %v", err, ErrDuplicate)
+	}
+	pb.Delete("show_DOG")
+	if pb.Has("Show_Dog") {
+		t.Errorf("Builder.Delete() didn't delete %v", "Show_Dog")
+	}
+
+	var cs Builder
+	cs.Set("Show_Dog", "/dogs/:id")
+	if cs.Has("show_dog") {
+		t.Errorf("Builder.Has(%v) = true, want false", "show_dog")
+	}
+}
+
+func TestBuilder_init(t *testing.T) {
+	var b Builder
+	b.init()
+	// This should not panic after we init
+	b.paths["key"] = compile("value", ColonStyle, "")
+}
+
+func TestExpand(t *testing.T) {
+	params := map[string]interface{}{"id": 123, "page": 2}
+	tests := []struct {
+		name   string
+		format string
+		query  bool
+		want   string
+	}{
+		{"without query", "/dogs/:id", false, "/dogs/123"},
+		{"with query", "/dogs/:id", true, "/dogs/123?page=2"},
+		{"missing param", "/dogs/:id/toys/:toy_id", false, "/dogs/123/toys/:toy_id"},
+		{"invalid format", "/files/*rest/edit", false, ""},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := Expand(tc.format, params, tc.query); got != tc.want {
+				t.Errorf("Expand() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func Test_replace(t *testing.T) {
+	type args struct {
+		path   string
+		params map[string]interface{}
+		query  bool
+	}
+	tests := []struct {
+		name      string
+		args      args
+		wantBase  string
+		wantQuery url.Values
+	}{
+		{
+			name: "no replacements",
+			args: args{
+				path:   "/some/path",
+				params: nil,
+				query:  false,
+			},
+			wantBase: "/some/path",
+		},
+		{
+			name: "id replacement",
+			args: args{
+				path: "/widgets/:id",
+				params: map[string]interface{}{
+					"id": 123,
+				},
+				query: false,
+			},
+			wantBase: "/widgets/123",
+		},
+		{
+			name: "query with no replacements",
+			args: args{
+				path: "/widgets/",
+				params: map[string]interface{}{
+					"id": 123,
+				},
+				query: true,
+			},
+			wantBase: "/widgets/",
+			wantQuery: url.Values{
+				"id": []string{"123"},
+			},
+		},
+		{
+			name: "query and replacements",
+			args: args{
+				path: "/widgets/:id/edit/:blah",
+				params: map[string]interface{}{
+					"id":   123,
+					"blah": "dog",
+					"name": "felix",
+				},
+				query: true,
+			},
+			wantBase: "/widgets/123/edit/dog",
+			wantQuery: url.Values{
+				"name": []string{"felix"},
+			},
+		},
+		{
+			name: "query with slices",
+			args: args{
+				path: "/widgets/",
+				params: map[string]interface{}{
+					"tag":  []string{"a", "b"},
+					"id":   []int{1, 2, 3},
+					"misc": []interface{}{"x", 4},
+					"arr":  [2]bool{true, false},
+					"name": "felix",
+				},
+				query: true,
+			},
+			wantBase: "/widgets/",
+			wantQuery: url.Values{
+				"tag":  []string{"a", "b"},
+				"id":   []string{"1", "2", "3"},
+				"misc": []string{"x", "4"},
+				"arr":  []string{"true", "false"},
+				"name": []string{"felix"},
+			},
+		},
+		{
+			name: "query replacements and missing param",
+			args: args{
+				path: "/widgets/:id/edit/:blah",
+				params: map[string]interface{}{
+					"id":   123,
+					"name": "felix",
+				},
+				query: true,
+			},
+			wantBase: "/widgets/123/edit/:blah",
+			wantQuery: url.Values{
+				"name": []string{"felix"},
+			},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := replace(tc.args.path, tc.args.params, config{query: tc.args.query})
+			if err != nil {
+				t.Fatalf("replace() err = %v, want %v", err, nil)
+			}
+			gotPieces := strings.SplitN(got, "?", 2)
+			gotBase := gotPieces[0]
+			if gotBase != tc.wantBase {
+				t.Errorf("replace() = %v, want %v", gotBase, tc.wantBase)
+			}
+			if !tc.args.query {
+				return
+			}
+			if len(gotPieces) != 2 {
+				t.Fatalf("replace() query = %v, want %v", nil, tc.wantQuery)
+			}
+			gotQ, err := url.ParseQuery(gotPieces[1])
+			if err != nil {
+				t.Fatalf("url.ParseQuery(%v) err = %v, want %v", gotPieces[1], err, nil)
+			}
+			if !reflect.DeepEqual(gotQ, tc.wantQuery) {
+				t.Errorf("replace() query = %v, want %v", gotQ, tc.wantQuery)
+			}
+		})
+	}
+}
+
+func Test_replace_missingParamMode(t *testing.T) {
+	tests := []struct {
+		name    string
+		path    string
+		mode    MissingParamMode
+		want    string
+		wantErr bool
+	}{
+		{"keep placeholder", "/widgets/:id/edit", KeepPlaceholder, "/widgets/:id/edit", false},
+		{"keep catch-all placeholder", "/files/*filepath", KeepPlaceholder, "/files/*filepath", false},
+		{"empty segment", "/widgets/:id/edit", EmptySegment, "/widgets//edit", false},
+		{"empty catch-all segment", "/files/*filepath", EmptySegment, "/files/", false},
+		{"return error", "/widgets/:id/edit", ReturnError, "", true},
+		{"no params to miss", "/widgets", ReturnError, "/widgets", false},
+		{"port", "http://localhost:8080/widgets", EmptySegment, "http://localhost:8080/widgets", false},
+		{"time", "/at/10:30", ReturnError, "/at/10:30", false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := replace(tc.path, nil, config{missing: tc.mode})
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("replace() err = %v, wantErr %v", err, tc.wantErr)
+			}
+			if got != tc.want {
+				t.Errorf("replace() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func Test_replace_multipleParams(t *testing.T) {
+	tests := []struct {
+		name   string
+		path   string
+		params map[string]interface{}
+		mode   MissingParamMode
+		want   string
+	}{
+		{"two params", "/items/:a-:b", map[string]interface{}{"a": "toys", "b": 123}, KeepPlaceholder, "/items/toys-123"},
+		{"prefix", "/users/prefix:id", map[string]interface{}{"id": 123}, KeepPlaceholder, "/users/prefix123"},
+		{"suffix", "/dogs/:id.json", map[string]interface{}{"id": 123}, KeepPlaceholder, "/dogs/123.json"},
+		{"csv suffix", "/reports/:id.csv", map[string]interface{}{"id": 42}, KeepPlaceholder, "/reports/42.csv"},
+		{"multi-part suffix", "/files/:name.tar.gz", map[string]interface{}{"name": "v1"}, KeepPlaceholder, "/files/v1.tar.gz"},
+		{"non-dot suffix", "/users/:id~edit", map[string]interface{}{"id": 7}, KeepPlaceholder, "/users/7~edit"},
+		{"missing with suffix", "/reports/:id.csv", nil, KeepPlaceholder, "/reports/:id.csv"},
+		{"escaped", "/items/:a-:b", map[string]interface{}{"a": "a b", "b": "c/d"}, KeepPlaceholder, "/items/a%20b-c%2Fd"},
+		{"keep placeholder", "/items/:a-:b", map[string]interface{}{"a": "toys"}, KeepPlaceholder, "/items/toys-:b"},
+		{"empty segment", "/items/:a-:b", map[string]interface{}{"a": "toys"}, EmptySegment, "/items/toys-"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := replace(tc.path, tc.params, config{missing: tc.mode})
+			if err != nil {
+				t.Fatalf("replace() err = %v, want %v", err, nil)
+			}
+			if got != tc.want {
+				t.Errorf("replace() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+	_, err := replace("/items/:a-:b", map[string]interface{}{"a": "toys"}, config{missing: ReturnError})
+	if mpe, ok := err.(*MissingParamError); !ok || mpe.Key != "b" {
+		t.Errorf("replace() err = %v, want missing %v", err, "b")
+	}
+}
+
+func Test_replace_inlineDefaults(t *testing.T) {
+	tests := []struct {
+		name   string
+		path   string
+		style  ParamStyle
+		params map[string]interface{}
+		mode   MissingParamMode
+		want   string
+	}{
+		{"absent uses default", "/posts/:page=1", ColonStyle, nil, KeepPlaceholder, "/posts/1"},
+		{"present overrides default", "/posts/:page=1", ColonStyle, map[string]interface{}{"page": 3}, KeepPlaceholder, "/posts/3"},
+		{"default is escaped", "/search/:q=a b", ColonStyle, nil, KeepPlaceholder, "/search/a%20b"},
+		{"default satisfies required params", "/posts/:page=1", ColonStyle, nil, ReturnError, "/posts/1"},
+		{"empty default", "/posts/:page=", ColonStyle, nil, ReturnError, "/posts/"},
+		{"default in a segment", "/reports/:id.:format=csv", ColonStyle, map[string]interface{}{"id": 42}, KeepPlaceholder, "/reports/42.csv"},
+		{"brace style", "/posts/{page=1}", BraceStyle, nil, KeepPlaceholder, "/posts/1"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := replace(tc.path, tc.params, config{style: tc.style, missing: tc.mode})
+			if err != nil {
+				t.Fatalf("replace() err = %v, want %v", err, nil)
+			}
+			if got != tc.want {
+				t.Errorf("replace() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func Test_replace_requireAll(t *testing.T) {
+	tests := []struct {
+		name    string
+		path    string
+		params  map[string]interface{}
+		want    string
+		wantKey string
+	}{
+		{"no params in path", "/widgets", nil, "/widgets", ""},
+		{"all params provided", "/widgets/:id", map[string]interface{}{"id": 123}, "/widgets/123", ""},
+		{"nil params", "/widgets/:id", nil, "", "id"},
+		{"one of many missing", "/widgets/:id/edit/:blah", map[string]interface{}{"id": 123}, "", "blah"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := replace(tc.path, tc.params, config{missing: ReturnError})
+			if tc.wantKey == "" && err != nil {
+				t.Fatalf("replace() err = %v, want %v", err, nil)
+			}
+			if tc.wantKey != "" {
+				mpe, ok := err.(*MissingParamError)
+				if !ok || mpe.Key != tc.wantKey {
+					t.Fatalf("replace() err = %v, want missing %v", err, tc.wantKey)
+				}
+			}
+			if got != tc.want {
+				t.Errorf("replace() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func Test_replace_escaping(t *testing.T) {
+	tests := []struct {
+		name   string
+		path   string
+		params map[string]interface{}
+		raw    bool
+		want   string
+	}{
+		{"escaped", "/posts/:slug", map[string]interface{}{"slug": "a/b c"}, false, "/posts/a%2Fb%20c"},
+		{"raw", "/posts/:slug", map[string]interface{}{"slug": "a/b c"}, true, "/posts/a/b c"},
+		{"catch-all slashes are not escaped", "/files/*filepath", map[string]interface{}{"filepath": "a/b c"}, false, "/files/a/b%20c"},
+		{"catch-all query and fragment", "/files/*filepath", map[string]interface{}{"filepath": "x?admin=1#y"}, false, "/files/x%3Fadmin=1%23y"},
+		{"raw catch-all", "/files/*filepath", map[string]interface{}{"filepath": "a/b c"}, true, "/files/a/b c"},
+		{"missing param is left as-is", "/posts/:slug", nil, false, "/posts/:slug"},
+		{"repeated param", "/posts/:slug/:slug", map[string]interface{}{"slug": "a b"}, false, "/posts/a%20b/a%20b"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := replace(tc.path, tc.params, config{raw: tc.raw})
+			if err != nil {
+				t.Fatalf("replace() err = %v, want %v", err, nil)
+			}
+			if got != tc.want {
+				t.Errorf("replace() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func Test_replace_formatter(t *testing.T) {
+	when := time.Date(2018, 7, 5, 12, 30, 0, 0, time.UTC)
+	formatter := func(v interface{}) string {
+		if t, ok := v.(time.Time); ok {
+			return t.Format("2006-01-02")
+		}
+		return fmt.Sprintf("<%v>", v)
+	}
+	tests := []struct {
+		name      string
+		formatter func(interface{}) string
+		want      string
+	}{
+		{"default", nil, "/posts/2018-07-05T12:30:00Z/1?tag=a&tag=b"},
+		{"formatter", formatter, "/posts/2018-07-05/%3C1%3E?tag=%3Ca%3E&tag=%3Cb%3E"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := replace("/posts/:date/:id", map[string]interface{}{
+				"date": when,
+				"id":   1,
+				"tag":  []string{"a", "b"},
+			}, config{query: true, formatter: tc.formatter})
+			if err != nil {
+				t.Fatalf("replace() err = %v, want %v", err, nil)
+			}
+			if got != tc.want {
+				t.Errorf("replace() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func Test_replace_nested(t *testing.T) {
+	params := map[string]interface{}{
+		"filter": map[string]interface{}{
+			"status": "active",
+			"sort":   "name",
+			"owner": map[string]string{
+				"name": "jane",
+			},
+			"tags": []string{"a", "b"},
+		},
+		"page": 2,
+		"ids":  map[int]string{1: "a"},
+	}
+	tests := []struct {
+		name   string
+		nested bool
+		want   url.Values
+	}{
+		{
+			name:   "nested",
+			nested: true,
+			want: url.Values{
+				"filter[status]":      []string{"active"},
+				"filter[sort]":        []string{"name"},
+				"filter[owner][name]": []string{"jane"},
+				"filter[tags]":        []string{"a", "b"},
+				"page":                []string{"2"},
+				"ids":                 []string{"map[1:a]"},
+			},
+		},
+		{
+			name:   "not nested",
+			nested: false,
+			want: url.Values{
+				"filter": []string{fmt.Sprintf("%v", params["filter"])},
+				"page":   []string{"2"},
+				"ids":    []string{"map[1:a]"},
+			},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := replace("/widgets", params, config{query: true, nested: tc.nested})
+			if err != nil {
+				t.Fatalf("replace() err = %v, want %v", err, nil)
+			}
+			pieces := strings.SplitN(got, "?", 2)
+			if len(pieces) != 2 {
+				t.Fatalf("replace() = %v, want a query", got)
+			}
+			gotQ, err := url.ParseQuery(pieces[1])
+			if err != nil {
+				t.Fatalf("url.ParseQuery(%v) err = %v, want %v", pieces[1], err, nil)
+			}
+			if !reflect.DeepEqual(gotQ, tc.want) {
+				t.Errorf("replace() query = %v, want %v", gotQ, tc.want)
+			}
+		})
+	}
+}
+
+func Test_replace_optional(t *testing.T) {
+	tests := []struct {
+		name    string
+		path    string
+		params  map[string]interface{}
+		cfg     config
+		want    string
+		wantErr bool
+	}{
+		{"present", "/posts/:id/:slug?", map[string]interface{}{"id": 1, "slug": "hello"}, config{}, "/posts/1/hello", false},
+		{"omitted", "/posts/:id/:slug?", map[string]interface{}{"id": 1}, config{}, "/posts/1", false},
+		{"omitted in the middle", "/posts/:slug?/edit", nil, config{}, "/posts/edit", false},
+		{"omitted with query", "/posts/:id/:slug?", map[string]interface{}{"id": 1, "page": 2}, config{query: true}, "/posts/1?page=2", false},
+		{"omitted brace style", "/posts/{id}/{slug?}", map[string]interface{}{"id": 1}, config{style: BraceStyle}, "/posts/1", false},
+		{"only segment omitted", "/:lang?", nil, config{}, "/", false},
+		{"omitted with require all", "/posts/:id/:slug?", map[string]interface{}{"id": 1}, config{missing: ReturnError}, "/posts/1", false},
+		{"required still missing", "/posts/:id/:slug?", nil, config{missing: ReturnError}, "", true},
+		{"required still kept", "/posts/:id/:slug?", nil, config{}, "/posts/:id", false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := replace(tc.path, tc.params, tc.cfg)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("replace() err = %v, wantErr %v", err, tc.wantErr)
+			}
+			if got != tc.want {
+				t.Errorf("replace() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func Test_replace_timeLayout(t *testing.T) {
+	when := time.Date(2018, 7, 5, 12, 30, 0, 0, time.FixedZone("EST", -5*60*60))
+	tests := []struct {
+		name      string
+		layout    string
+		formatter func(interface{}) string
+		want      string
+	}{
+		{"default layout", "", nil, "/posts/2018-07-05T12:30:00-05:00?at=2018-07-05T12%3A30%3A00-05%3A00"},
+		{"custom layout", "2006-01-02 15:04", nil, "/posts/2018-07-05%2012:30?at=2018-07-05+12%3A30"},
+		{"formatter takes precedence", "2006", func(interface{}) string { return "x" }, "/posts/x?at=x"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			params := map[string]interface{}{"date": when, "at": when}
+			got, err := replace("/posts/:date", params, config{
+				query:      true,
+				timeLayout: tc.layout,
+				formatter:  tc.formatter,
+			})
+			if err != nil {
+				t.Fatalf("replace() err = %v, want %v", err, nil)
+			}
+			if got != tc.want {
+				t.Errorf("replace() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func Test_replace_valueFormatting(t *testing.T) {
+	type id int
+	tests := []struct {
+		name  string
+		value interface{}
+		want  string
+	}{
+		{"int", 123, "123"},
+		{"negative int", -123, "-123"},
+		{"int64", int64(9007199254740993), "9007199254740993"},
+		{"uint8", uint8(255), "255"},
+		{"float64", 1.5, "1.5"},
+		{"float64 whole", float64(2), "2"},
+		{"float64 small", 0.000001, "1e-06"},
+		{"float32", float32(0.1), "0.1"},
+		{"bool true", true, "true"},
+		{"bool false", false, "false"},
+		{"named int", id(7), "7"},
+		{"text marshaler", textID{1, 2}, "1:2"},
+		{"text marshaler pointer", &textID{1, 2}, "1:2"},
+		{"text marshaler error", badText{"x"}, "{x}"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			params := map[string]interface{}{"v": tc.value, "q": tc.value}
+			got, err := replace("/values/:v", params, config{query: true})
+			if err != nil {
+				t.Fatalf("replace() err = %v, want %v", err, nil)
+			}
+			want := "/values/" + url.PathEscape(tc.want) + "?q=" + url.QueryEscape(tc.want)
+			if got != want {
+				t.Errorf("replace() = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+type textID struct {
+	a, b int
+}
+
+func (id textID) MarshalText() ([]byte, error) {
+	return []byte(fmt.Sprintf("%d:%d", id.a, id.b)), nil
+}
+
+type badText struct {
+	v string
+}
+
+func (badText) MarshalText() ([]byte, error) {
+	return nil, fmt.Errorf("bad text")
+}
+
+func Test_replace_omitEmpty(t *testing.T) {
+	params := map[string]interface{}{
+		"q":     "",
+		"nil":   nil,
+		"zero":  0,
+		"false": false,
+		"name":  "felix",
+		"tags":  []string{"", "a"},
+	}
+	tests := []struct {
+		name      string
+		omitEmpty bool
+		want      string
+	}{
+		{"kept", false, "/search?false=false&name=felix&nil=&q=&tags=&tags=a&zero=0"},
+		{"omitted", true, "/search?false=false&name=felix&tags=a&zero=0"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := replace("/search", params, config{query: true, omitEmpty: tc.omitEmpty})
+			if err != nil {
+				t.Fatalf("replace() err = %v, want %v", err, nil)
+			}
+			if got != tc.want {
+				t.Errorf("replace() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+	got, err := replace("/search", map[string]interface{}{"q": ""}, config{query: true, omitEmpty: true})
+	if err != nil {
+		t.Fatalf("replace() err = %v, want %v", err, nil)
+	}
+	if want := "/search"; got != want {
+		t.Errorf("replace() = %v, want %v", got, want)
+	}
+}
+
+func TestInQuery(t *testing.T) {
+	tests := []struct {
+		name   string
+		path   string
+		params map[string]interface{}
 		query  bool
+		want   string
+	}{
+		{"path and query", "/users/:id/posts", map[string]interface{}{"id": InQuery(1)}, true, "/users/1/posts?id=1"},
+		{"not in path", "/users/posts", map[string]interface{}{"id": InQuery(1)}, true, "/users/posts?id=1"},
+		{"slice", "/tags/:tag", map[string]interface{}{"tag": InQuery([]string{"a", "b"})}, true, "/tags/%5Ba%20b%5D?tag=a&tag=b"},
+		{"without InQuery", "/users/:id/posts", map[string]interface{}{"id": 1}, true, "/users/1/posts"},
+		{"query disabled", "/users/:id/posts", map[string]interface{}{"id": InQuery(1)}, false, "/users/1/posts"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := replace(tc.path, tc.params, config{query: tc.query})
+			if err != nil {
+				t.Fatalf("replace() err = %v, want %v", err, nil)
+			}
+			if got != tc.want {
+				t.Errorf("replace() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func Test_replace_queryKey(t *testing.T) {
+	snake := func(k string) string {
+		var sb strings.Builder
+		for _, r := range k {
+			if r >= 'A' && r <= 'Z' {
+				sb.WriteByte('_')
+				r += 'a' - 'A'
+			}
+			sb.WriteRune(r)
+		}
+		return sb.String()
+	}
+	params := map[string]interface{}{
+		"userId":  3,
+		"sortBy":  "name",
+		"dogId":   1,
+		"page":    2,
+		"tagList": []string{"a", "b"},
 	}
 	tests := []struct {
-		name      string
-		args      args
-		wantBase  string
-		wantQuery url.Values
+		name     string
+		queryKey func(string) string
+		want     string
 	}{
-		{
-			name: "no replacements",
-			args: args{
-				path:   "/some/path",
-				params: nil,
-				query:  false,
-			},
-			wantBase: "/some/path",
-		},
-		{
-			name: "id replacement",
-			args: args{
-				path: "/widgets/:id",
-				params: map[string]interface{}{
-					"id": 123,
-				},
-				query: false,
-			},
-			wantBase: "/widgets/123",
-		},
-		{
-			name: "query with no replacements",
-			args: args{
-				path: "/widgets/",
-				params: map[string]interface{}{
-					"id": 123,
-				},
-				query: true,
-			},
-			wantBase: "/widgets/",
-			wantQuery: url.Values{
-				"id": []string{"123"},
-			},
-		},
-		{
-			name: "query and replacements",
-			args: args{
-				path: "/widgets/:id/edit/:blah",
-				params: map[string]interface{}{
-					"id":   123,
-					"blah": "dog",
-					"name": "felix",
-				},
-				query: true,
-			},
-			wantBase: "/widgets/123/edit/dog",
-			wantQuery: url.Values{
-				"name": []string{"felix"},
-			},
-		},
-		{
-			name: "query replacements and missing param",
-			args: args{
-				path: "/widgets/:id/edit/:blah",
-				params: map[string]interface{}{
-					"id":   123,
-					"name": "felix",
-				},
-				query: true,
-			},
-			wantBase: "/widgets/123/edit/:blah",
-			wantQuery: url.Values{
-				"name": []string{"felix"},
-			},
-		},
+		{"identity", nil, "/dogs/1?page=2&sortBy=name&tagList=a&tagList=b&userId=3"},
+		{"snake case", snake, "/dogs/1?page=2&sort_by=name&tag_list=a&tag_list=b&user_id=3"},
 	}
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
-			got := replace(tc.args.path, tc.args.params, tc.args.query)
-			gotPieces := strings.SplitN(got, "?", 2)
-			gotBase := gotPieces[0]
-			if gotBase != tc.wantBase {
-				t.Errorf("replace() = %v, want %v", gotBase, tc.wantBase)
+			got, err := replace("/dogs/:dogId", params, config{query: true, queryKey: tc.queryKey})
+			if err != nil {
+				t.Fatalf("replace() err = %v, want %v", err, nil)
 			}
-			if !tc.args.query {
-				return
+			if got != tc.want {
+				t.Errorf("replace() = %v, want %v", got, tc.want)
 			}
-			if len(gotPieces) != 2 {
-				t.Fatalf("replace() query = %v, want %v", nil, tc.wantQuery)
+		})
+	}
+}
+
+func Test_replace_spaceAsPercent(t *testing.T) {
+	params := map[string]interface{}{
+		"q":      "hot dogs",
+		"plus":   "a+b",
+		"search": "a +b",
+	}
+	tests := []struct {
+		name         string
+		spacePercent bool
+		want         string
+	}{
+		{"plus", false, "/dogs?plus=a%2Bb&q=hot+dogs&search=a+%2Bb"},
+		{"percent", true, "/dogs?plus=a%2Bb&q=hot%20dogs&search=a%20%2Bb"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := replace("/dogs", params, config{query: true, spacePercent: tc.spacePercent})
+			if err != nil {
+				t.Fatalf("replace() err = %v, want %v", err, nil)
 			}
-			gotQ, err := url.ParseQuery(gotPieces[1])
+			if got != tc.want {
+				t.Errorf("replace() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func Test_replace_encoding(t *testing.T) {
+	params := map[string]interface{}{
+		"q":    "hot dogs",
+		"plus": "a+b",
+		"next": "/dogs?id=1&a=b",
+		"at":   "me@example.com:80",
+		"path": InQuery("a b+c/d"),
+	}
+	tests := []struct {
+		name     string
+		encoding Encoding
+		want     string
+	}{
+		{"form", FormEncoding, "/a%20b+c%2Fd?at=me%40example.com%3A80&next=%2Fdogs%3Fid%3D1%26a%3Db&path=a+b%2Bc%2Fd&plus=a%2Bb&q=hot+dogs"},
+		{"rfc 3986", RFC3986Encoding, "/a%20b+c%2Fd?at=me@example.com:80&next=/dogs?id%3D1%26a%3Db&path=a%20b%2Bc/d&plus=a%2Bb&q=hot%20dogs"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := replace("/:path", params, config{query: true, encoding: tc.encoding})
 			if err != nil {
-				t.Fatalf("url.ParseQuery(%v) err = %v, want %v", gotPieces[1], err, nil)
+				t.Fatalf("replace() err = %v, want %v", err, nil)
 			}
-			if !reflect.DeepEqual(gotQ, tc.wantQuery) {
-				t.Errorf("replace() query = %v, want %v", gotQ, tc.wantQuery)
+			if got != tc.want {
+				t.Errorf("replace() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func Test_appendEscaped(t *testing.T) {
+	var all []byte
+	for c := 0; c < 256; c++ {
+		all = append(all, byte(c))
+	}
+	s := string(all) + "héllo wörld"
+	if got, want := string(appendEscaped(nil, s, escapePath)), url.PathEscape(s); got != want {
+		t.Errorf("appendEscaped(escapePath) = %v, want %v", got, want)
+	}
+	if got, want := string(appendEscaped(nil, s, escapeForm)), url.QueryEscape(s); got != want {
+		t.Errorf("appendEscaped(escapeForm) = %v, want %v", got, want)
+	}
+	want := strings.Replace(url.QueryEscape(s), "+", "%20", -1)
+	if got := string(appendEscaped(nil, s, escapeFormPercent)); got != want {
+		t.Errorf("appendEscaped(escapeFormPercent) = %v, want %v", got, want)
+	}
+}
+
+func Test_replace_matrix(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+		params map[string]interface{}
+		matrix bool
+		want   string
+	}{
+		{"filled", "/items;color=red/:id", map[string]interface{}{"color": "blue", "id": 1}, true, "/items;color=blue/1"},
+		{"value in format", "/items;color=red/:id", map[string]interface{}{"id": 1}, true, "/items;color=red/1"},
+		{"without value", "/items;color;size/:id", map[string]interface{}{"size": 10, "id": 1}, true, "/items;size=10/1"},
+		{"after a param", "/items/:id;color=red", map[string]interface{}{"id": 1, "color": "dark blue"}, true, "/items/1;color=dark%20blue"},
+		{"not in query", "/items;color=red", map[string]interface{}{"color": "blue", "page": 2}, true, "/items;color=blue?page=2"},
+		{"off", "/items;color=red/:id", map[string]interface{}{"color": "blue", "id": 1}, false, "/items;color=red/1?color=blue"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := replace(tc.format, tc.params, config{query: true, matrix: tc.matrix})
+			if err != nil {
+				t.Fatalf("replace() err = %v, want %v", err, nil)
+			}
+			if got != tc.want {
+				t.Errorf("replace() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func Test_replace_indexedArray(t *testing.T) {
+	many := make([]int, 12)
+	for i := range many {
+		many[i] = i
+	}
+	tests := []struct {
+		name    string
+		value   interface{}
+		indexed bool
+		want    string
+	}{
+		{"empty", []int{}, true, "/dogs?name=felix"},
+		{"one element", []int{1}, true, "/dogs?ids%5B0%5D=1&name=felix"},
+		{"two elements", []string{"a", "b"}, true, "/dogs?ids%5B0%5D=a&ids%5B1%5D=b&name=felix"},
+		{"array", [2]bool{true, false}, true, "/dogs?ids%5B0%5D=true&ids%5B1%5D=false&name=felix"},
+		{"more than ten elements", many, true, "/dogs?ids%5B0%5D=0&ids%5B1%5D=1&ids%5B2%5D=2&ids%5B3%5D=3&ids%5B4%5D=4&ids%5B5%5D=5&ids%5B6%5D=6&ids%5B7%5D=7&ids%5B8%5D=8&ids%5B9%5D=9&ids%5B10%5D=10&ids%5B11%5D=11&name=felix"},
+		{"not indexed", []int{1, 2}, false, "/dogs?ids=1&ids=2&name=felix"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			params := map[string]interface{}{"ids": tc.value, "name": "felix"}
+			got, err := replace("/dogs", params, config{query: true, indexed: tc.indexed})
+			if err != nil {
+				t.Fatalf("replace() err = %v, want %v", err, nil)
+			}
+			if got != tc.want {
+				t.Errorf("replace() = %v, want %v", got, tc.want)
 			}
 		})
 	}
 }
 
-func Test_key(t *testing.T) {
+func Test_replace_catchAll(t *testing.T) {
 	tests := []struct {
 		name    string
-		arg     string
+		path    string
+		params  map[string]interface{}
 		want    string
 		wantErr error
 	}{
-		{"valid key", ":id", "id", nil},
-		{"invalid key", "id", "", errInvalidKey},
+		{"value with slashes", "/files/*filepath", map[string]interface{}{"filepath": "a/b/c.txt"}, "/files/a/b/c.txt", nil},
+		{"missing value", "/files/*filepath", nil, "/files/*filepath", nil},
+		{"with other params", "/users/:id/files/*filepath", map[string]interface{}{"id": 1, "filepath": "a/b.txt"}, "/users/1/files/a/b.txt", nil},
+		{"not the final segment", "/files/*filepath/edit", map[string]interface{}{"filepath": "a.txt"}, "", ErrCatchAllPos},
+		{"not the final segment without params", "/files/*filepath/edit", nil, "", ErrCatchAllPos},
 	}
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
-			got, err := key(tc.arg)
+			got, err := replace(tc.path, tc.params, config{})
 			if err != tc.wantErr {
-				t.Errorf("key() error = %v, wantErr %v", err, tc.wantErr)
-				return
+				t.Fatalf("replace() err = %v, want %v", err, tc.wantErr)
+			}
+			if got != tc.want {
+				t.Errorf("replace() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func Test_replace_trailingSlash(t *testing.T) {
+	tests := []struct {
+		name   string
+		path   string
+		params map[string]interface{}
+		mode   TrailingSlashMode
+		want   string
+	}{
+		{"as is without slash", "/dogs/:id", map[string]interface{}{"id": 1}, TrailingSlashAsIs, "/dogs/1"},
+		{"as is with slash", "/dogs/", nil, TrailingSlashAsIs, "/dogs/"},
+		{"always adds slash", "/dogs/:id", map[string]interface{}{"id": 1}, TrailingSlashAlways, "/dogs/1/"},
+		{"always keeps slash", "/dogs/", nil, TrailingSlashAlways, "/dogs/"},
+		{"always with query", "/dogs", map[string]interface{}{"age": 12}, TrailingSlashAlways, "/dogs/?age=12"},
+		{"never removes slash", "/dogs/", nil, TrailingSlashNever, "/dogs"},
+		{"never without slash", "/dogs/:id", map[string]interface{}{"id": 1}, TrailingSlashNever, "/dogs/1"},
+		{"never with query", "/dogs/", map[string]interface{}{"age": 12}, TrailingSlashNever, "/dogs?age=12"},
+		{"never keeps root", "/", nil, TrailingSlashNever, "/"},
+		{"always root", "/", nil, TrailingSlashAlways, "/"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := replace(tc.path, tc.params, config{query: true, slash: tc.mode})
+			if err != nil {
+				t.Fatalf("replace() err = %v, want %v", err, nil)
+			}
+			if got != tc.want {
+				t.Errorf("replace() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func BenchmarkBuilder_StrictPath(b *testing.B) {
+	var pb Builder
+	pb.Set("show_toy", "/users/:user_id/dogs/:dog_id/toys/:toy_id/colors/:color/edit")
+	params := map[string]interface{}{
+		"user_id": 1,
+		"dog_id":  2,
+		"toy_id":  3,
+		"color":   "red",
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		pb.StrictPath("show_toy", params)
+	}
+}
+
+func BenchmarkBuilder_StrictPath_query(b *testing.B) {
+	var pb Builder
+	pb.Set("show_toy", "/users/:user_id/dogs/:dog_id/toys/:toy_id/colors/:color/edit")
+	params := map[string]interface{}{
+		"user_id": 1,
+		"dog_id":  2,
+		"toy_id":  3,
+		"color":   "red",
+		"page":    4,
+		"sort":    "name",
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		pb.StrictPath("show_toy", params)
+	}
+}
+
+func BenchmarkBuilder_StrictPath_parallel(b *testing.B) {
+	var pb Builder
+	pb.Set("show_toy", "/users/:user_id/dogs/:dog_id/toys/:toy_id/colors/:color/edit")
+	params := map[string]interface{}{
+		"user_id": 1,
+		"dog_id":  2,
+		"toy_id":  3,
+		"color":   "red",
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pbt *testing.PB) {
+		for pbt.Next() {
+			pb.StrictPath("show_toy", params)
+		}
+	})
+}
+
+func Test_replace_separator(t *testing.T) {
+	tests := []struct {
+		name   string
+		path   string
+		params map[string]interface{}
+		sep    string
+		want   string
+	}{
+		{"dot", "a.b.:id.c", map[string]interface{}{"id": 1}, ".", "a.b.1.c"},
+		{"dot with slash in value", "a.:id", map[string]interface{}{"id": "x/y"}, ".", "a.x%2Fy"},
+		{"missing param", "a.:id.c", nil, ".", "a.:id.c"},
+		{"optional param", "a.:id?", nil, ".", "a"},
+		{"composite", "a.:id-:slug", map[string]interface{}{"id": 1, "slug": "x"}, ".", "a.1-x"},
+		{"multiple characters", "a::b:::id", map[string]interface{}{"id": 1}, "::", "a::b::1"},
+		{"catch-all", "a.*rest", map[string]interface{}{"rest": "b.c"}, ".", "a.b.c"},
+		{"default", "/a/:id", map[string]interface{}{"id": 1}, "", "/a/1"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := replace(tc.path, tc.params, config{sep: tc.sep})
+			if err != nil {
+				t.Fatalf("replace() err = %v, want %v", err, nil)
+			}
+			if got != tc.want {
+				t.Errorf("replace() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func Test_replace_lowercase(t *testing.T) {
+	tests := []struct {
+		name   string
+		path   string
+		params map[string]interface{}
+		lower  bool
+		want   string
+	}{
+		{"literal segments", "/Dogs/New", nil, true, "/dogs/new"},
+		{"param values", "/dogs/:name", map[string]interface{}{"name": "Rex"}, true, "/dogs/rex"},
+		{"escaped values", "/dogs/:name", map[string]interface{}{"name": "A/B C"}, true, "/dogs/a%2Fb%20c"},
+		{"escaped non-ASCII", "/dogs/:name", map[string]interface{}{"name": "É"}, true, "/dogs/%C3%89"},
+		{"query", "/Dogs", map[string]interface{}{"Name": "Rex"}, true, "/dogs?Name=Rex"},
+		{"off", "/Dogs/:name", map[string]interface{}{"name": "Rex"}, false, "/Dogs/Rex"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := replace(tc.path, tc.params, config{query: true, lower: tc.lower})
+			if err != nil {
+				t.Fatalf("replace() err = %v, want %v", err, nil)
+			}
+			if got != tc.want {
+				t.Errorf("replace() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func Test_replace_nil(t *testing.T) {
+	tests := []struct {
+		name      string
+		path      string
+		params    map[string]interface{}
+		missing   MissingParamMode
+		omitEmpty bool
+		want      string
+		wantErr   bool
+	}{
+		{"path keeps placeholder", "/dogs/:id", map[string]interface{}{"id": nil}, KeepPlaceholder, false, "/dogs/:id", false},
+		{"path empty segment", "/dogs/:id/edit", map[string]interface{}{"id": nil}, EmptySegment, false, "/dogs//edit", false},
+		{"path error", "/dogs/:id", map[string]interface{}{"id": nil}, ReturnError, false, "", true},
+		{"path inline default", "/dogs/:page=1", map[string]interface{}{"page": nil}, KeepPlaceholder, false, "/dogs/1", false},
+		{"path optional", "/dogs/:id?", map[string]interface{}{"id": nil}, KeepPlaceholder, false, "/dogs", false},
+		{"path in query", "/dogs/:id", map[string]interface{}{"id": InQuery(nil)}, KeepPlaceholder, false, "/dogs/:id?id=", false},
+		{"query empty", "/dogs", map[string]interface{}{"q": nil}, KeepPlaceholder, false, "/dogs?q=", false},
+		{"query omitted", "/dogs", map[string]interface{}{"q": nil}, KeepPlaceholder, true, "/dogs", false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := replace(tc.path, tc.params, config{query: true, missing: tc.missing, omitEmpty: tc.omitEmpty})
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("replace() err = %v, wantErr %v", err, tc.wantErr)
+			}
+			if got != tc.want {
+				t.Errorf("replace() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func Test_replace_bareBoolFlags(t *testing.T) {
+	params := map[string]interface{}{
+		"active":  true,
+		"deleted": false,
+		"flags":   []bool{true, false, true},
+		"name":    "true",
+	}
+	tests := []struct {
+		name string
+		bare bool
+		want string
+	}{
+		{"off", false, "/dogs?active=true&deleted=false&flags=true&flags=false&flags=true&name=true"},
+		{"on", true, "/dogs?active&flags&flags&name=true"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := replace("/dogs", params, config{query: true, bareBools: tc.bare})
+			if err != nil {
+				t.Fatalf("replace() err = %v, want %v", err, nil)
+			}
+			if got != tc.want {
+				t.Errorf("replace() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func Test_replace_collapseSlashes(t *testing.T) {
+	tests := []struct {
+		name     string
+		path     string
+		params   map[string]interface{}
+		collapse bool
+		want     string
+	}{
+		{"leading", "//dogs/:id", map[string]interface{}{"id": 1}, true, "/dogs/1"},
+		{"middle", "/dogs//:id///edit", map[string]interface{}{"id": 1}, true, "/dogs/1/edit"},
+		{"trailing", "/dogs/:id//", map[string]interface{}{"id": 1}, true, "/dogs/1/"},
+		{"empty param", "/dogs/:id/edit", map[string]interface{}{"id": ""}, true, "/dogs/edit"},
+		{"escaped param", "/dogs/:id", map[string]interface{}{"id": "a//b"}, true, "/dogs/a%2F%2Fb"},
+		{"query", "/dogs//", map[string]interface{}{"next": "//a"}, true, "/dogs/?next=%2F%2Fa"},
+		{"off", "//dogs//:id", map[string]interface{}{"id": 1}, false, "//dogs//1"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := replace(tc.path, tc.params, config{query: true, collapse: tc.collapse})
+			if err != nil {
+				t.Fatalf("replace() err = %v, want %v", err, nil)
 			}
 			if got != tc.want {
-				t.Errorf("key() = %v, want %v", got, tc.want)
+				t.Errorf("replace() = %v, want %v", got, tc.want)
 			}
 		})
 	}
@@ -0,0 +1,698 @@
+package path
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// pattern is the compiled form of a path's format. Formats
+// are compiled once when they are set so that building a path
+// doesn't require splitting and parsing the format every
+// time.
+type pattern struct {
+	// format is the original, uncompiled format.
+	format string
+	// style is the ParamStyle the format was compiled with.
+	style ParamStyle
+	// sep is the separator between segments, eg `/`.
+	sep string
+	// segments are the pieces of the format between slashes.
+	segments []segment
+	// keys are the names of every param in the format.
+	keys map[string]bool
+	// err is set if the format is invalid, eg because a
+	// catch-all param isn't the final segment.
+	err error
+	// src is the format as it was set when it references
+	// other paths, eg `{dogs}/:id`. format is then the format
+	// with those references inlined, eg `/dogs/:id`.
+	src string
+	// fn is used to get the format for paths set with
+	// SetFunc.
+	fn func() string
+	// defaults are the values of params with an inline
+	// default, eg `1` for `:page=1`.
+	defaults map[string]string
+	// opts are the options provided when the path was set.
+	opts Options
+	// types are the types of params set with SetTyped, eg
+	// "int" for `:id`.
+	types map[string]string
+	// matrix are the keys of every param in the format,
+	// including matrix params, eg `color` for
+	// `/items;color=red`. It is nil if there are no matrix
+	// params.
+	matrix map[string]bool
+}
+
+// segment is a single piece of a format. Segments that are
+// not params have an empty key.
+type segment struct {
+	// value is the text of the segment as it appears in the
+	// format, eg `dogs` or `:id`.
+	value string
+	// key is the name of the param, eg `id` for `:id`.
+	key string
+	// parts is set for segments that mix params and text, eg
+	// `:category-:id` or `:id.json`. These segments have an
+	// empty key.
+	parts []part
+	// catchAll is whether or not the param is a catch-all.
+	catchAll bool
+	// optional is whether or not the param is optional, eg
+	// `:slug?`. Optional params without a value are removed
+	// from the path along with their leading slash.
+	optional bool
+}
+
+// compile compiles format into a pattern, splitting it into
+// segments at each sep, or at each slash if sep is empty.
+func compile(format string, style ParamStyle, sep string) *pattern {
+	if sep == "" {
+		sep = "/"
+	}
+	pieces := strings.Split(format, sep)
+	p := &pattern{
+		format:   format,
+		style:    style,
+		sep:      sep,
+		segments: make([]segment, len(pieces)),
+		keys:     make(map[string]bool),
+	}
+	for i, piece := range pieces {
+		p.segments[i].value = piece
+		var k string
+		parts := tokenize(piece, style)
+		for _, pt := range parts {
+			if v, ok := pt.def(); ok {
+				if p.defaults == nil {
+					p.defaults = make(map[string]string)
+				}
+				p.defaults[pt.key] = v
+			}
+		}
+		if !isCatchAll(piece) {
+			for _, pt := range parts {
+				if pt.key == "" {
+					p.addMatrix(pt.value)
+				}
+			}
+		}
+		switch {
+		case isCatchAll(piece):
+			k = piece[1:]
+			p.segments[i].catchAll = true
+			if i != len(pieces)-1 {
+				p.err = ErrCatchAllPos
+			}
+		case len(parts) == 1 && parts[0].key != "":
+			k = parts[0].key
+		case len(parts) == 2 && parts[0].key != "" && parts[1].value == "?":
+			// An optional ColonStyle param, eg `:slug?`
+			k = parts[0].key + "?"
+		case len(parts) > 1:
+			p.segments[i].parts = parts
+			for _, pt := range parts {
+				if pt.key != "" {
+					p.keys[pt.key] = true
+				}
+			}
+			continue
+		default:
+			continue
+		}
+		if len(k) > 1 && strings.HasSuffix(k, "?") {
+			k = k[:len(k)-1]
+			p.segments[i].optional = true
+		}
+		p.segments[i].key = k
+		p.keys[k] = true
+	}
+	if p.matrix != nil {
+		for k := range p.keys {
+			p.matrix[k] = true
+		}
+	}
+	return p
+}
+
+// addMatrix adds the keys of any matrix params in the text of
+// a segment, eg `color` for `items;color=red`, to p.matrix.
+func (p *pattern) addMatrix(text string) {
+	i := strings.IndexByte(text, ';')
+	if i < 0 {
+		return
+	}
+	if p.matrix == nil {
+		p.matrix = make(map[string]bool)
+	}
+	for _, kv := range strings.Split(text[i+1:], ";") {
+		if k := strings.SplitN(kv, "=", 2)[0]; k != "" {
+			p.matrix[k] = true
+		}
+	}
+}
+
+// validate returns an error describing the first invalid
+// segment in the pattern, if any. A segment is invalid if it
+// has a param without a valid name, eg `:` or `{}`, or if it
+// is a catch-all param that isn't the final segment.
+func (p *pattern) validate() error {
+	for i, seg := range p.segments {
+		if seg.catchAll && i != len(p.segments)-1 {
+			return fmt.Errorf("path: invalid segment %q in %q: %w", seg.value, p.format, ErrCatchAllPos)
+		}
+		parts := seg.parts
+		if parts == nil {
+			parts = []part{{value: seg.value, key: seg.key}}
+		}
+		for _, pt := range parts {
+			if !pt.valid(p.style) {
+				return fmt.Errorf("path: invalid segment %q in %q: param names must be letters, digits, and underscores", seg.value, p.format)
+			}
+		}
+	}
+	return nil
+}
+
+// valid returns whether or not the part is a param with a
+// valid name, or text that doesn't look like a param without
+// one, eg a lone `:` or `*`.
+func (pt part) valid(style ParamStyle) bool {
+	if pt.key != "" {
+		for _, r := range pt.key {
+			if !isIdent(r) {
+				return false
+			}
+		}
+		return true
+	}
+	if pt.value == "*" {
+		return false
+	}
+	if style == BraceStyle {
+		return !strings.ContainsAny(pt.value, "{}")
+	}
+	// A colon followed by a digit is a port or a time, eg
+	// `localhost:8080` or `10:30`, but any other colon is a
+	// param with an invalid name.
+	for s := pt.value; strings.Contains(s, ":"); {
+		s = s[strings.IndexByte(s, ':')+1:]
+		if r, _ := utf8.DecodeRuneInString(s); !unicode.IsDigit(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// part is a piece of a segment that mixes params and text.
+// Parts that are not params have an empty key.
+type part struct {
+	// value is the text of the part as it appears in the
+	// format, eg `-` or `:id`.
+	value string
+	// key is the name of the param, eg `id` for `:id`.
+	key string
+}
+
+// tokenize splits a segment into params and the text between
+// them. With the ColonStyle a param is a `:` followed by a
+// letter or underscore and then letters, digits, and
+// underscores, so `:category-:id` is the
+// param `category`, the text `-`, and the param `id`. With the
+// BraceStyle a param is anything between `{` and `}`.
+func tokenize(piece string, style ParamStyle) []part {
+	var parts []part
+	lit := 0
+	for i := 0; i < len(piece); {
+		end, k := i+1, ""
+		switch style {
+		case BraceStyle:
+			if j := strings.IndexByte(piece[i:], '}'); piece[i] == '{' && j > 1 {
+				end, k = i+j+1, piece[i+1:i+j]
+				if eq := strings.IndexByte(k, '='); eq >= 0 {
+					k = k[:eq]
+				}
+			}
+		default:
+			if r, _ := utf8.DecodeRuneInString(piece[i+1:]); piece[i] == ':' && isIdentStart(r) {
+				end = i + 1
+				for end < len(piece) {
+					r, size := utf8.DecodeRuneInString(piece[end:])
+					if !isIdent(r) {
+						break
+					}
+					end += size
+				}
+				if k = piece[i+1 : end]; end < len(piece) && piece[end] == '=' {
+					// The default is the rest of the segment
+					end = len(piece)
+				}
+			}
+		}
+		if k == "" {
+			i = end
+			continue
+		}
+		if lit < i {
+			parts = append(parts, part{value: piece[lit:i]})
+		}
+		parts = append(parts, part{value: piece[i:end], key: k})
+		i, lit = end, end
+	}
+	if lit < len(piece) {
+		parts = append(parts, part{value: piece[lit:]})
+	}
+	return parts
+}
+
+// def returns the inline default of a param part, eg `1` for
+// `:page=1` or `{page=1}`.
+func (pt part) def() (string, bool) {
+	i := strings.IndexByte(pt.value, '=')
+	if pt.key == "" || i < 0 {
+		return "", false
+	}
+	v := pt.value[i+1:]
+	if pt.value[0] == '{' {
+		v = strings.TrimSuffix(v, "}")
+	}
+	return v, true
+}
+
+// isIdent returns whether or not r can be used in the name of
+// a ColonStyle param.
+func isIdent(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// isIdentStart returns whether or not r can be the first rune
+// in the name of a ColonStyle param.
+func isIdentStart(r rune) bool {
+	return r == '_' || unicode.IsLetter(r)
+}
+
+// current returns the format the pattern should be compiled
+// with, which may differ from its format for paths set with
+// SetFunc.
+func (p *pattern) current() string {
+	if p.fn != nil {
+		return p.fn()
+	}
+	return p.raw()
+}
+
+// raw returns the format of the pattern as it was set, before
+// any references to other paths were inlined.
+func (p *pattern) raw() string {
+	if p.src != "" {
+		return p.src
+	}
+	return p.format
+}
+
+// stale returns whether or not the pattern needs to be
+// compiled again, either because it was compiled with another
+// style or separator or because its current format has
+// changed. raw is the current format as set and format is the
+// same format with references to other paths inlined.
+func (p *pattern) stale(style ParamStyle, sep, raw, format string) bool {
+	return p.segments == nil || p.style != style || p.sep != sep || p.raw() != raw || p.format != format
+}
+
+// expand builds a path from the pattern by replacing each of
+// its params with the value provided for it in params.
+func (p *pattern) expand(params map[string]interface{}, cfg config) (string, error) {
+	buf := bufPool.Get().(*[]byte)
+	defer bufPool.Put(buf)
+	ret, err := p.appendTo((*buf)[:0], params, cfg)
+	*buf = ret
+	if err != nil {
+		return "", err
+	}
+	return string(ret), nil
+}
+
+// appendTo is like expand, but the path is appended to dst.
+func (p *pattern) appendTo(dst []byte, params map[string]interface{}, cfg config) ([]byte, error) {
+	if p.err != nil {
+		return dst, p.err
+	}
+	start := len(dst)
+	var err error
+	for i, seg := range p.segments {
+		_, ok := lookup(params, seg.key)
+		if seg.key != "" && !ok && seg.optional {
+			continue
+		}
+		if i > 0 {
+			dst = append(dst, p.sep...)
+		}
+		if seg.parts != nil {
+			for _, pt := range seg.parts {
+				if dst, err = p.appendParam(dst, pt.key, pt.value, false, params, cfg); err != nil {
+					return dst, err
+				}
+			}
+			continue
+		}
+		if dst, err = p.appendParam(dst, seg.key, seg.value, seg.catchAll, params, cfg); err != nil {
+			return dst, err
+		}
+	}
+	if len(dst) == start && strings.HasPrefix(p.format, p.sep) {
+		// Every segment was an optional param without a value
+		dst = append(dst, p.sep...)
+	}
+	if cfg.collapse {
+		dst = collapseSlashes(dst, start)
+	}
+	dst = appendTrailingSlash(dst, start, cfg.slash)
+	if cfg.lower {
+		lowercase(dst[start:])
+	}
+	keys := p.keys
+	if cfg.matrix && p.matrix != nil {
+		keys = p.matrix
+	}
+	if !cfg.query || fills(params, keys) {
+		if len(cfg.values) == 0 {
+			return dst, nil
+		}
+		params = nil
+	}
+	q := cfg.collect(params, keys)
+	defer q.release()
+	if len(q.pairs) == 0 {
+		return dst, nil
+	}
+	dst = append(dst, '?')
+	return q.appendTo(dst), nil
+}
+
+// appendParam appends the value of the param named key to dst.
+// If key is empty value is appended as is.
+func (p *pattern) appendParam(dst []byte, key, value string, catchAll bool, params map[string]interface{}, cfg config) ([]byte, error) {
+	if key == "" {
+		if cfg.matrix && p.matrix != nil {
+			return appendMatrix(dst, value, params, cfg), nil
+		}
+		return append(dst, value...), nil
+	}
+	v, ok := lookup(params, key)
+	if d, hasDef := p.defaults[key]; !ok && hasDef {
+		v, ok = d, true
+	}
+	switch {
+	case !ok && cfg.missing == ReturnError:
+		return dst, &MissingParamError{Key: key}
+	case !ok && cfg.missing == EmptySegment:
+	case !ok:
+		dst = append(dst, value...)
+	case cfg.raw:
+		dst = append(dst, cfg.str(v)...)
+	case catchAll:
+		// Escape each segment, but keep the separators
+		for i, s := range strings.Split(cfg.str(v), p.sep) {
+			if i > 0 {
+				dst = append(dst, p.sep...)
+			}
+			dst = appendEscaped(dst, s, escapePath)
+		}
+	default:
+		dst = appendEscaped(dst, cfg.str(v), escapePath)
+	}
+	return dst, nil
+}
+
+// lookup returns the value of the param key, unwrapping it if
+// it was wrapped with InQuery, and whether or not it has one.
+// A nil value is treated the same as no value, so that it
+// isn't built as `<nil>`.
+func lookup(params map[string]interface{}, key string) (interface{}, bool) {
+	v, _ := unwrapInQuery(params[key])
+	return v, v != nil
+}
+
+// names returns the names of the params in the pattern in the
+// order they first appear.
+func (p *pattern) names() []string {
+	names := make([]string, 0, len(p.keys))
+	seen := make(map[string]bool, len(p.keys))
+	add := func(k string) {
+		if k != "" && !seen[k] {
+			seen[k] = true
+			names = append(names, k)
+		}
+	}
+	for _, seg := range p.segments {
+		add(seg.key)
+		for _, pt := range seg.parts {
+			add(pt.key)
+		}
+	}
+	return names
+}
+
+// appendMatrix appends the text of a segment to dst, filling
+// in the values of any matrix params in it, eg
+// `items;color=red` becomes `items;color=blue` when the param
+// color is blue. The value in the text is used when the param
+// has no value, and matrix params without either, eg
+// `items;color`, are left out.
+func appendMatrix(dst []byte, text string, params map[string]interface{}, cfg config) []byte {
+	i := strings.IndexByte(text, ';')
+	if i < 0 {
+		return append(dst, text...)
+	}
+	dst = append(dst, text[:i]...)
+	for _, kv := range strings.Split(text[i+1:], ";") {
+		k, def, hasDef := kv, "", false
+		if j := strings.IndexByte(kv, '='); j >= 0 {
+			k, def, hasDef = kv[:j], kv[j+1:], true
+		}
+		v, ok := lookup(params, k)
+		switch {
+		case k == "":
+			dst = append(dst, ';')
+			dst = append(dst, kv...)
+			continue
+		case ok:
+			def = cfg.str(v)
+			if !cfg.raw {
+				def = string(appendEscaped(nil, def, escapePath))
+			}
+		case !hasDef:
+			continue
+		}
+		dst = append(dst, ';')
+		dst = append(dst, k...)
+		dst = append(dst, '=')
+		dst = append(dst, def...)
+	}
+	return dst
+}
+
+// checkExtra returns an *ExtraParamsError if any of the
+// params don't fill a param in the pattern and aren't wrapped
+// with InQuery.
+func (p *pattern) checkExtra(params map[string]interface{}, cfg config) error {
+	keys := p.keys
+	if cfg.matrix && p.matrix != nil {
+		keys = p.matrix
+	}
+	var extra []string
+	for k, v := range params {
+		if _, inQuery := unwrapInQuery(v); !keys[k] && !inQuery {
+			extra = append(extra, k)
+		}
+	}
+	if len(extra) == 0 {
+		return nil
+	}
+	sort.Strings(extra)
+	return &ExtraParamsError{Keys: extra}
+}
+
+// fills returns whether or not every key in params is in keys,
+// meaning there are no query params.
+func fills(params map[string]interface{}, keys map[string]bool) bool {
+	for k, v := range params {
+		if !keys[k] {
+			return false
+		}
+		if _, inQuery := unwrapInQuery(v); inQuery {
+			return false
+		}
+	}
+	return true
+}
+
+// match is the reverse of expand. See Builder.Match for
+// details.
+func (p *pattern) match(path string) (map[string]string, bool) {
+	if p.err != nil {
+		return nil, false
+	}
+	if i := strings.IndexByte(path, '?'); i >= 0 {
+		path = path[:i]
+	}
+	pieces := strings.Split(path, p.sep)
+	// When the path has fewer segments than the pattern, the
+	// last optional params are treated as missing.
+	var skip map[int]bool
+	if n := len(p.segments) - len(pieces); n > 0 {
+		skip = make(map[int]bool)
+		for i := len(p.segments) - 1; i >= 0 && n > 0; i-- {
+			if p.segments[i].optional {
+				skip[i] = true
+				n--
+			}
+		}
+		if n > 0 {
+			return nil, false
+		}
+	}
+	params := make(map[string]string)
+	j := 0
+	for i, seg := range p.segments {
+		if skip[i] {
+			continue
+		}
+		if j >= len(pieces) {
+			return nil, false
+		}
+		piece := pieces[j]
+		j++
+		if seg.parts != nil {
+			if !matchParts(seg.parts, piece, params) {
+				return nil, false
+			}
+			continue
+		}
+		if seg.key == "" {
+			if seg.value != piece {
+				return nil, false
+			}
+			continue
+		}
+		if seg.catchAll {
+			rest := pieces[j-1:]
+			for i, s := range rest {
+				v, err := url.PathUnescape(s)
+				if err != nil {
+					return nil, false
+				}
+				rest[i] = v
+			}
+			params[seg.key] = strings.Join(rest, p.sep)
+			return params, true
+		}
+		if !matchParam(seg.key, piece, params) {
+			return nil, false
+		}
+	}
+	if j != len(pieces) {
+		return nil, false
+	}
+	if p.types != nil {
+		if _, ok := p.convert(params); !ok {
+			return nil, false
+		}
+	}
+	return params, true
+}
+
+// convert converts the values of the params to the types set
+// with SetTyped. Params without a type are left as strings.
+func (p *pattern) convert(params map[string]string) (map[string]interface{}, bool) {
+	ret := make(map[string]interface{}, len(params))
+	for k, v := range params {
+		var err error
+		switch p.types[k] {
+		case "", "string":
+			ret[k] = v
+		case "int":
+			ret[k], err = strconv.Atoi(v)
+		case "bool":
+			ret[k], err = strconv.ParseBool(v)
+		default:
+			return nil, false
+		}
+		if err != nil {
+			return nil, false
+		}
+	}
+	return ret, true
+}
+
+// matchParts matches a segment that mixes params and text,
+// eg `:category-:id`, against piece. Each param ends at the
+// first occurrence of the text that follows it, except for
+// text at the end of the segment which must be a suffix, so
+// `:id.json` matches `1.2.json` with the id `1.2`.
+func matchParts(parts []part, piece string, params map[string]string) bool {
+	for i, pt := range parts {
+		if pt.key == "" {
+			if !strings.HasPrefix(piece, pt.value) {
+				return false
+			}
+			piece = piece[len(pt.value):]
+			continue
+		}
+		end := len(piece)
+		switch {
+		case i == len(parts)-1:
+		case parts[i+1].key != "":
+			// Adjacent params can't be told apart
+			return false
+		case i+1 == len(parts)-1:
+			if !strings.HasSuffix(piece, parts[i+1].value) {
+				return false
+			}
+			end = len(piece) - len(parts[i+1].value)
+		default:
+			if end = strings.Index(piece, parts[i+1].value); end < 0 {
+				return false
+			}
+		}
+		if end == 0 || !matchParam(pt.key, piece[:end], params) {
+			return false
+		}
+		piece = piece[end:]
+	}
+	return piece == ""
+}
+
+// matchParam unescapes value and sets it as the value of the
+// param named key.
+func matchParam(key, value string, params map[string]string) bool {
+	v, err := url.PathUnescape(value)
+	if err != nil {
+		return false
+	}
+	// A param used more than once must have the same value
+	// every time.
+	if prev, ok := params[key]; ok && prev != v {
+		return false
+	}
+	params[key] = v
+	return true
+}
+
+// literals returns the number of segments in the pattern that
+// are not params.
+func (p *pattern) literals() int {
+	n := 0
+	for _, seg := range p.segments {
+		if seg.key == "" && seg.parts == nil {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,157 @@
+package path
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_compile(t *testing.T) {
+	tests := []struct {
+		name     string
+		format   string
+		style    ParamStyle
+		segments []segment
+		keys     map[string]bool
+		err      error
+	}{
+		{
+			name:   "no params",
+			format: "/dogs",
+			segments: []segment{
+				{value: ""},
+				{value: "dogs"},
+			},
+			keys: map[string]bool{},
+		},
+		{
+			name:   "params",
+			format: "/dogs/:id/edit/*rest",
+			segments: []segment{
+				{value: ""},
+				{value: "dogs"},
+				{value: ":id", key: "id"},
+				{value: "edit"},
+				{value: "*rest", key: "rest", catchAll: true},
+			},
+			keys: map[string]bool{"id": true, "rest": true},
+		},
+		{
+			name:   "brace style",
+			format: "/dogs/{id}/:name",
+			style:  BraceStyle,
+			segments: []segment{
+				{value: ""},
+				{value: "dogs"},
+				{value: "{id}", key: "id"},
+				{value: ":name"},
+			},
+			keys: map[string]bool{"id": true},
+		},
+		{
+			name:   "optional",
+			format: "/posts/:slug?",
+			segments: []segment{
+				{value: ""},
+				{value: "posts"},
+				{value: ":slug?", key: "slug", optional: true},
+			},
+			keys: map[string]bool{"slug": true},
+		},
+		{
+			name:   "multiple params in a segment",
+			format: "/items/:category-:id.json",
+			segments: []segment{
+				{value: ""},
+				{value: "items"},
+				{value: ":category-:id.json", parts: []part{
+					{value: ":category", key: "category"},
+					{value: "-"},
+					{value: ":id", key: "id"},
+					{value: ".json"},
+				}},
+			},
+			keys: map[string]bool{"category": true, "id": true},
+		},
+		{
+			name:   "catch-all not last",
+			format: "/files/*rest/edit",
+			segments: []segment{
+				{value: ""},
+				{value: "files"},
+				{value: "*rest", key: "rest", catchAll: true},
+				{value: "edit"},
+			},
+			keys: map[string]bool{"rest": true},
+			err:  ErrCatchAllPos,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := compile(tc.format, tc.style, "")
+			if got.format != tc.format || got.style != tc.style {
+				t.Errorf("compile() = %v (%v), want %v (%v)", got.format, got.style, tc.format, tc.style)
+			}
+			if !reflect.DeepEqual(got.segments, tc.segments) {
+				t.Errorf("compile() segments = %v, want %v", got.segments, tc.segments)
+			}
+			if !reflect.DeepEqual(got.keys, tc.keys) {
+				t.Errorf("compile() keys = %v, want %v", got.keys, tc.keys)
+			}
+			if got.err != tc.err {
+				t.Errorf("compile() err = %v, want %v", got.err, tc.err)
+			}
+		})
+	}
+}
+
+func Test_tokenize(t *testing.T) {
+	tests := []struct {
+		name  string
+		piece string
+		style ParamStyle
+		want  []part
+	}{
+		{"empty", "", ColonStyle, nil},
+		{"text", "dogs", ColonStyle, []part{{value: "dogs"}}},
+		{"param", ":id", ColonStyle, []part{{value: ":id", key: "id"}}},
+		{"two params", ":a-:b", ColonStyle, []part{{value: ":a", key: "a"}, {value: "-"}, {value: ":b", key: "b"}}},
+		{"prefix", "prefix:id", ColonStyle, []part{{value: "prefix"}, {value: ":id", key: "id"}}},
+		{"suffix", ":id.json", ColonStyle, []part{{value: ":id", key: "id"}, {value: ".json"}}},
+		{"underscores and digits", ":user_id2", ColonStyle, []part{{value: ":user_id2", key: "user_id2"}}},
+		{"bare colon", "a:-b", ColonStyle, []part{{value: "a:-b"}}},
+		{"port", "localhost:8080", ColonStyle, []part{{value: "localhost:8080"}}},
+		{"time", "10:30", ColonStyle, []part{{value: "10:30"}}},
+		{"leading digit", ":1id", ColonStyle, []part{{value: ":1id"}}},
+		{"leading underscore", ":_id", ColonStyle, []part{{value: ":_id", key: "_id"}}},
+		{"default", ":page=1", ColonStyle, []part{{value: ":page=1", key: "page"}}},
+		{"default after text", "p:page=a-b", ColonStyle, []part{{value: "p"}, {value: ":page=a-b", key: "page"}}},
+		{"brace default", "{page=1}.json", BraceStyle, []part{{value: "{page=1}", key: "page"}, {value: ".json"}}},
+		{"brace param", "{id}", BraceStyle, []part{{value: "{id}", key: "id"}}},
+		{"brace params", "{a}-{b}.json", BraceStyle, []part{{value: "{a}", key: "a"}, {value: "-"}, {value: "{b}", key: "b"}, {value: ".json"}}},
+		{"unclosed brace", "{id", BraceStyle, []part{{value: "{id"}}},
+		{"empty brace", "{}", BraceStyle, []part{{value: "{}"}}},
+		{"colon with brace style", ":id", BraceStyle, []part{{value: ":id"}}},
+		{"brace with colon style", "{id}", ColonStyle, []part{{value: "{id}"}}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tokenize(tc.piece, tc.style); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("tokenize() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestBuilder_pattern(t *testing.T) {
+	var pb Builder
+	pb.Set("show_dog", "/dogs/{id}")
+	// Changing the style after Set should recompile the pattern
+	pb.ParamStyle = BraceStyle
+	got, err := pb.StrictPath("show_dog", map[string]interface{}{"id": 123})
+	if err != nil {
+		t.Fatalf("Builder.StrictPath() error = %v, want %v", err, nil)
+	}
+	if want := "/dogs/123"; got != want {
+		t.Errorf("Builder.StrictPath() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,59 @@
+package path
+
+import "strings"
+
+// RelPath builds the paths named from and to with the same
+// params, and returns the path of to relative to from, eg
+// `../2` from `/dogs/1/edit` to `/dogs/2`. The relative path
+// is resolved the same way a browser resolves a link, so
+// from's final segment is dropped unless it ends with a
+// slash. Params that aren't in to are added to the query as
+// usual, after the relative path, but params that aren't in
+// from are never an error since they may be meant for the
+// `to` path. The BaseURL is not used, so both paths are
+// assumed to be on the same host.
+func (b *Builder) RelPath(from, to string, params map[string]interface{}) (string, error) {
+	cfg := b.config()
+	cfg.baseURL = ""
+	target, err := b.build(to, params, cfg)
+	if err != nil {
+		return "", err
+	}
+	cfg.query, cfg.disallow = false, false
+	base, err := b.build(from, params, cfg)
+	if err != nil {
+		return "", err
+	}
+	if i := strings.IndexByte(base, '?'); i >= 0 {
+		base = base[:i]
+	}
+	var query string
+	if i := strings.IndexByte(target, '?'); i >= 0 {
+		target, query = target[:i], target[i:]
+	}
+	return rel(base, target) + query, nil
+}
+
+// rel returns the relative path that resolves to target when
+// it is resolved against base.
+func rel(base, target string) string {
+	dir := strings.Split(base[:strings.LastIndexByte(base, '/')+1], "/")
+	segs := strings.Split(target, "/")
+	// Both end in the final segment, which is empty for dir
+	i := 0
+	for i < len(dir)-1 && i < len(segs)-1 && dir[i] == segs[i] {
+		i++
+	}
+	var sb strings.Builder
+	for j := i; j < len(dir)-1; j++ {
+		sb.WriteString("../")
+	}
+	sb.WriteString(strings.Join(segs[i:], "/"))
+	ret := sb.String()
+	if ret == "" || strings.IndexByte(strings.SplitN(ret, "/", 2)[0], ':') >= 0 {
+		// An empty path would resolve to base itself, and a
+		// colon in the first segment would look like a scheme.
+		ret = "./" + ret
+	}
+	return ret
+}
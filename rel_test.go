@@ -0,0 +1,59 @@
+package path
+
+import (
+	"errors"
+	"net/url"
+	"testing"
+)
+
+func TestBuilder_RelPath(t *testing.T) {
+	pb := Builder{BaseURL: "https://example.com"}
+	pb.Set("dogs", "/dogs")
+	pb.Set("dogs_index", "/dogs/")
+	pb.Set("show_dog", "/dogs/:id")
+	pb.Set("edit_dog", "/dogs/:id/edit")
+	pb.Set("show_cat", "/cats/:id")
+	pb.Set("home", "/")
+	pb.Set("time", "/dogs/:time")
+	tests := []struct {
+		name   string
+		from   string
+		to     string
+		params map[string]interface{}
+		want   string
+	}{
+		{"sibling", "edit_dog", "show_dog", map[string]interface{}{"id": 1}, "../1"},
+		{"child", "show_dog", "edit_dog", map[string]interface{}{"id": 1}, "1/edit"},
+		{"parent", "show_dog", "dogs", map[string]interface{}{"id": 1}, "../dogs?id=1"},
+		{"directory", "show_dog", "dogs_index", map[string]interface{}{"id": 1}, "./?id=1"},
+		{"same path", "show_dog", "show_dog", map[string]interface{}{"id": 1}, "1"},
+		{"other tree", "edit_dog", "show_cat", map[string]interface{}{"id": 1}, "../../cats/1"},
+		{"root", "edit_dog", "home", map[string]interface{}{"id": 1}, "../../?id=1"},
+		{"from root", "home", "edit_dog", map[string]interface{}{"id": 1}, "dogs/1/edit"},
+		{"query", "edit_dog", "show_dog", map[string]interface{}{"id": 1, "page": 2}, "../1?page=2"},
+		{"colon", "dogs_index", "time", map[string]interface{}{"time": "10:30"}, "./10:30"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := pb.RelPath(tc.from, tc.to, tc.params)
+			if err != nil {
+				t.Fatalf("Builder.RelPath() error = %v, want %v", err, nil)
+			}
+			if got != tc.want {
+				t.Errorf("Builder.RelPath() = %v, want %v", got, tc.want)
+			}
+			// The relative path must resolve to the target
+			from, _ := pb.URL(tc.from, tc.params)
+			from.RawQuery = ""
+			to, _ := pb.StrictPath(tc.to, tc.params)
+			ref, _ := url.Parse(got)
+			if resolved := from.ResolveReference(ref).String(); resolved != to {
+				t.Errorf("resolved RelPath() = %v, want %v", resolved, to)
+			}
+		})
+	}
+
+	if _, err := pb.RelPath("unknown", "show_dog", nil); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Builder.RelPath() error = %v, want %v", err, ErrNotFound)
+	}
+}
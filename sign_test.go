@@ -0,0 +1,111 @@
+package path
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBuilder_SignedPath(t *testing.T) {
+	secret := []byte("secret")
+	pb := Builder{BaseURL: "https://example.com"}
+	pb.Set("download", "/downloads/:id")
+
+	got, err := pb.SignedPath("download", map[string]interface{}{"id": 1, "b": 2, "a": "x y"}, secret)
+	if err != nil {
+		t.Fatalf("Builder.SignedPath() error = %v, want %v", err, nil)
+	}
+	if want := "https://example.com/downloads/1?a=x+y&b=2&sig="; !strings.HasPrefix(got, want) {
+		t.Errorf("Builder.SignedPath() = %v, want prefix %v", got, want)
+	}
+	sig := got[strings.Index(got, "sig="):]
+
+	tests := []struct {
+		name   string
+		rawURL string
+		secret string
+		want   bool
+	}{
+		{"valid", got, "secret", true},
+		{"no base", "/downloads/1?a=x+y&b=2&" + sig, "secret", true},
+		{"reordered", "/downloads/1?" + sig + "&b=2&a=x%20y", "secret", true},
+		{"wrong secret", got, "other", false},
+		{"changed path", "/downloads/2?a=x+y&b=2&" + sig, "secret", false},
+		{"changed query", "/downloads/1?a=x+y&b=3&" + sig, "secret", false},
+		{"added query", "/downloads/1?a=x+y&b=2&c=1&" + sig, "secret", false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ok, err := pb.VerifySigned(tc.rawURL, []byte(tc.secret))
+			if err != nil {
+				t.Fatalf("Builder.VerifySigned() error = %v, want %v", err, nil)
+			}
+			if ok != tc.want {
+				t.Errorf("Builder.VerifySigned() = %v, want %v", ok, tc.want)
+			}
+		})
+	}
+
+	got, err = pb.SignedPath("download", map[string]interface{}{"id": 1}, secret)
+	if err != nil {
+		t.Fatalf("Builder.SignedPath() error = %v, want %v", err, nil)
+	}
+	if ok, _ := pb.VerifySigned(got, secret); !ok {
+		t.Errorf("Builder.VerifySigned(%v) = %v, want %v", got, ok, true)
+	}
+	if _, err := pb.VerifySigned("/downloads/1", secret); !errors.Is(err, ErrNoSignature) {
+		t.Errorf("Builder.VerifySigned() error = %v, want %v", err, ErrNoSignature)
+	}
+	if _, err := pb.SignedPath("download", map[string]interface{}{"id": 1, "sig": "x"}, secret); err == nil {
+		t.Errorf("Builder.SignedPath() error = %v, want an error", err)
+	}
+	if _, err := pb.SignedPath("unknown", nil, secret); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Builder.SignedPath() error = %v, want %v", err, ErrNotFound)
+	}
+}
+
+func TestBuilder_SignedPathExpiring(t *testing.T) {
+	secret := []byte("secret")
+	now := time.Unix(1700000000, 0)
+	pb := Builder{Now: func() time.Time { return now }}
+	pb.Set("download", "/downloads/:id")
+
+	got, err := pb.SignedPathExpiring("download", map[string]interface{}{"id": 1, "a": 2}, secret, now.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("Builder.SignedPathExpiring() error = %v, want %v", err, nil)
+	}
+	if want := "/downloads/1?a=2&exp=1700003600&sig="; !strings.HasPrefix(got, want) {
+		t.Errorf("Builder.SignedPathExpiring() = %v, want prefix %v", got, want)
+	}
+
+	tests := []struct {
+		name    string
+		rawURL  string
+		now     time.Time
+		want    bool
+		wantErr error
+	}{
+		{"valid", got, now, true, nil},
+		{"at expiry", got, now.Add(time.Hour), true, nil},
+		{"expired", got, now.Add(time.Hour + time.Second), false, ErrExpired},
+		{"changed expiry", strings.Replace(got, "exp=1700003600", "exp=1800000000", 1), now, false, nil},
+		{"removed expiry", strings.Replace(got, "exp=1700003600&", "", 1), now, false, nil},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			now = tc.now
+			ok, err := pb.VerifySigned(tc.rawURL, secret)
+			if err != tc.wantErr {
+				t.Fatalf("Builder.VerifySigned() error = %v, want %v", err, tc.wantErr)
+			}
+			if ok != tc.want {
+				t.Errorf("Builder.VerifySigned() = %v, want %v", ok, tc.want)
+			}
+		})
+	}
+
+	if _, err := pb.SignedPathExpiring("download", map[string]interface{}{"id": 1, "exp": 1}, secret, now); err == nil {
+		t.Errorf("Builder.SignedPathExpiring() error = %v, want an error", err)
+	}
+}
@@ -0,0 +1,102 @@
+package path
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// PathStruct is like StrictPath, but the params are read from
+// the fields of the struct v rather than a map. The `path`
+// struct tag is used as the param's key, falling back to the
+// lowercased field name when no tag is present. Eg:
+//
+//	type Dog struct {
+//	  ID   int `path:"id"`
+//	  Name string
+//	}
+//
+// would fill the params `:id` and `:name`. Unexported fields
+// and fields tagged with `path:"-"` are skipped. v may be a
+// struct or a pointer to one; anything else is an error.
+//
+// Fields that aren't in the path are added to the query. See
+// the URLTags option to control this with `url` struct tags.
+func (b *Builder) PathStruct(name string, v interface{}) (string, error) {
+	var keys map[string]bool
+	if b.URLTags {
+		names, err := b.Params(name)
+		if err != nil {
+			return "", err
+		}
+		keys = make(map[string]bool, len(names))
+		for _, k := range names {
+			keys[k] = true
+		}
+	}
+	params, err := structParams(v, keys)
+	if err != nil {
+		return "", err
+	}
+	return b.StrictPath(name, params)
+}
+
+// structParams returns the params for the fields of v. If keys
+// is nil every field is used, otherwise only fields that are
+// in keys or have a `url` tag are used.
+func structParams(v interface{}, keys map[string]bool) (map[string]interface{}, error) {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return nil, fmt.Errorf("path: PathStruct requires a struct, got a nil %T", v)
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("path: PathStruct requires a struct, got %T", v)
+	}
+	rt := rv.Type()
+	params := make(map[string]interface{})
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		if field.PkgPath != "" {
+			// unexported field
+			continue
+		}
+		key := field.Tag.Get("path")
+		if key == "-" {
+			continue
+		}
+		if key == "" {
+			key = strings.ToLower(field.Name)
+		}
+		fv := rv.Field(i)
+		if keys != nil && !keys[key] {
+			tag, ok := field.Tag.Lookup("url")
+			if !ok {
+				continue
+			}
+			opts := strings.Split(tag, ",")
+			if key = opts[0]; key == "-" {
+				continue
+			}
+			if key == "" {
+				key = field.Name
+			}
+			if hasOpt(opts[1:], "omitempty") && fv.IsZero() {
+				continue
+			}
+		}
+		params[key] = fv.Interface()
+	}
+	return params, nil
+}
+
+func hasOpt(opts []string, opt string) bool {
+	for _, o := range opts {
+		if o == opt {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,96 @@
+package path
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBuilder_PathStruct(t *testing.T) {
+	type dog struct {
+		ID   int `path:"id"`
+		Name string
+	}
+	var pb Builder
+	pb.Set("show_dog", "/dogs/:id")
+	pb.Set("named_dog", "/dogs/:id/:name")
+	tests := []struct {
+		name    string
+		path    string
+		arg     interface{}
+		want    string
+		wantErr bool
+	}{
+		{"struct", "named_dog", dog{ID: 123, Name: "felix"}, "/dogs/123/felix", false},
+		{"pointer to struct", "named_dog", &dog{ID: 123, Name: "felix"}, "/dogs/123/felix", false},
+		{"extra fields are query params", "show_dog", dog{ID: 123, Name: "felix"}, "/dogs/123?name=felix", false},
+		{"nil pointer", "show_dog", (*dog)(nil), "", true},
+		{"not a struct", "show_dog", map[string]interface{}{"id": 123}, "", true},
+		{"missing path", "fake_path", dog{}, "", true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := pb.PathStruct(tc.path, tc.arg)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("Builder.PathStruct() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if got != tc.want {
+				t.Errorf("Builder.PathStruct() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func Test_structParams(t *testing.T) {
+	type widget struct {
+		ID      int `path:"widget_id"`
+		Color   string
+		Skipped string `path:"-"`
+		secret  string
+	}
+	got, err := structParams(widget{ID: 1, Color: "red", Skipped: "a", secret: "b"}, nil)
+	if err != nil {
+		t.Fatalf("structParams() err = %v, want %v", err, nil)
+	}
+	want := map[string]interface{}{
+		"widget_id": 1,
+		"color":     "red",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("structParams() = %v, want %v", got, want)
+	}
+}
+
+func TestBuilder_PathStruct_urlTags(t *testing.T) {
+	type search struct {
+		ID       int    `path:"id"`
+		Sort     string `url:"sort,omitempty"`
+		Page     int    `url:"page"`
+		Query    string `url:",omitempty"`
+		Debug    bool   `url:"-"`
+		Untagged string
+	}
+	pb := Builder{URLTags: true}
+	pb.Set("dog_toys", "/dogs/:id/toys")
+	tests := []struct {
+		name string
+		arg  search
+		want string
+	}{
+		{"zero values", search{ID: 1}, "/dogs/1/toys?page=0"},
+		{"all values", search{ID: 1, Sort: "name", Page: 2, Query: "ball", Debug: true, Untagged: "x"}, "/dogs/1/toys?Query=ball&page=2&sort=name"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := pb.PathStruct("dog_toys", tc.arg)
+			if err != nil {
+				t.Fatalf("Builder.PathStruct() error = %v, want %v", err, nil)
+			}
+			if got != tc.want {
+				t.Errorf("Builder.PathStruct() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+	if _, err := pb.PathStruct("fake_path", search{}); err == nil {
+		t.Errorf("Builder.PathStruct() error = %v, want an error", err)
+	}
+}